@@ -0,0 +1,234 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+// The backlink package provides an index of the links between META objects
+// so that the objects which link to a given object can be found, for example
+// the ERNs which reference a particular SoundRecording.
+package backlink
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/meta-network/go-meta"
+)
+
+// Backlink is a link to an object from a property of another object.
+type Backlink struct {
+	// From is the CID of the object which contains the link.
+	From *cid.Cid
+
+	// Path is the path of the property within the From object which
+	// contains the link (e.g. ["children", "0"]).
+	Path []string
+
+	// To is the CID of the linked object.
+	To *cid.Cid
+}
+
+// Indexer is a META indexer which records the links contained in META
+// objects in a SQLite3 database so that they can be followed backwards.
+type Indexer struct {
+	indexDB *sql.DB
+	store   *meta.Store
+}
+
+// NewIndexer returns an Indexer which updates the index in the given SQLite3
+// database connection, getting META objects from the given META store.
+func NewIndexer(indexDB *sql.DB, store *meta.Store) (*Indexer, error) {
+	// migrate the db to ensure it has an up-to-date schema
+	if err := migrations.Run(indexDB); err != nil {
+		return nil, err
+	}
+
+	return &Indexer{
+		indexDB: indexDB,
+		store:   store,
+	}, nil
+}
+
+// Index indexes the links of the objects in a stream of META object links.
+func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
+	for {
+		select {
+		case id, ok := <-stream:
+			if !ok {
+				return nil
+			}
+			obj, err := i.store.Get(id)
+			if err != nil {
+				return err
+			}
+			if err := i.IndexObject(obj); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// IndexStore brings the index up to date with the objects in the store,
+// indexing objects which have not been indexed and removing objects which
+// are no longer in the store.
+//
+// It is only needed if objects have been written to the store without the
+// indexer having been added to it with meta.Store.AddIndex, which keeps the
+// index up to date as objects are put and deleted.
+func (i *Indexer) IndexStore(ctx context.Context) error {
+	indexed, err := i.indexedIDs()
+	if err != nil {
+		return err
+	}
+	iter, err := i.store.AllCids(ctx)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.Next() {
+		id := iter.Cid().String()
+		if indexed[id] {
+			delete(indexed, id)
+			continue
+		}
+		obj, err := i.store.Get(iter.Cid())
+		if err != nil {
+			return err
+		}
+		if err := i.IndexObject(obj); err != nil {
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	for id := range indexed {
+		if err := i.removeObject(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexObject records the links contained in the given object, doing nothing
+// if the object has already been indexed.
+func (i *Indexer) IndexObject(obj *meta.Object) error {
+	links, err := obj.Links()
+	if err != nil {
+		return err
+	}
+	tx, err := i.indexDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		`INSERT OR IGNORE INTO object (id) VALUES ($1)`,
+		obj.Cid().String(),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return nil
+	}
+	for _, link := range links {
+		_, err := tx.Exec(
+			`INSERT INTO backlink (from_id, path, to_id) VALUES ($1, $2, $3)`,
+			obj.Cid().String(), strings.Join(link.Path, "/"), link.Cid.String(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveObject removes the object with the given CID and the links it
+// contains from the index.
+func (i *Indexer) RemoveObject(id *cid.Cid) error {
+	return i.removeObject(id.String())
+}
+
+func (i *Indexer) removeObject(id string) error {
+	tx, err := i.indexDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM backlink WHERE from_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM object WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// indexedIDs returns the CIDs of the objects which have been indexed.
+func (i *Indexer) indexedIDs() (map[string]bool, error) {
+	rows, err := i.indexDB.Query(`SELECT id FROM object`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// Backlinks returns the indexed links to the object with the given CID,
+// ordered by the CID and path of the objects which contain them.
+func (i *Indexer) Backlinks(id *cid.Cid) ([]*Backlink, error) {
+	rows, err := i.indexDB.Query(
+		`SELECT from_id, path FROM backlink WHERE to_id = $1 ORDER BY from_id, path`,
+		id.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var backlinks []*Backlink
+	for rows.Next() {
+		var fromID, path string
+		if err := rows.Scan(&fromID, &path); err != nil {
+			return nil, err
+		}
+		from, err := cid.Decode(fromID)
+		if err != nil {
+			return nil, err
+		}
+		backlinks = append(backlinks, &Backlink{
+			From: from,
+			Path: strings.Split(path, "/"),
+			To:   id,
+		})
+	}
+	return backlinks, rows.Err()
+}
@@ -0,0 +1,196 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package backlink
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/meta-network/go-meta"
+)
+
+// TestBacklinks tests indexing the objects in a store and querying the
+// links to an object.
+func TestBacklinks(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "backlink-index-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := meta.NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *meta.Object {
+		obj := meta.MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	recording := put(map[string]interface{}{"title": "recording"})
+	ern1 := put(map[string]interface{}{"recordings": []*cid.Cid{recording.Cid()}})
+	ern2 := put(map[string]interface{}{"release": map[string]interface{}{"recording": recording.Cid()}})
+
+	indexer, err := NewIndexer(db, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.IndexStore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// check indexing the store again does not duplicate links
+	if err := indexer.IndexStore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	backlinks, err := indexer.Backlinks(recording.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		ern1.Cid().String(): {"recordings", "0"},
+		ern2.Cid().String(): {"release", "recording"},
+	}
+	if len(backlinks) != len(expected) {
+		t.Fatalf("expected %d backlinks, got %d", len(expected), len(backlinks))
+	}
+	for _, backlink := range backlinks {
+		if !backlink.To.Equals(recording.Cid()) {
+			t.Fatalf("unexpected backlink target: %s", backlink.To)
+		}
+		path, ok := expected[backlink.From.String()]
+		if !ok {
+			t.Fatalf("unexpected backlink from %s", backlink.From)
+		}
+		if !reflect.DeepEqual(backlink.Path, path) {
+			t.Fatalf("unexpected backlink path from %s: %v", backlink.From, backlink.Path)
+		}
+	}
+
+	// check an object with no links to it has no backlinks
+	backlinks, err = indexer.Backlinks(ern1.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 0 {
+		t.Fatalf("expected no backlinks, got %d", len(backlinks))
+	}
+}
+
+// TestStoreIndex tests that an indexer added to a store is updated as
+// objects are put, committed in a batch and deleted, and that IndexStore
+// removes objects deleted while the indexer was not added.
+func TestStoreIndex(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "backlink-index-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := meta.NewStore(datastore.NewMapDatastore())
+	indexer, err := NewIndexer(db, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.AddIndex(indexer)
+
+	assertBacklinks := func(id *cid.Cid, expected ...*meta.Object) {
+		backlinks, err := indexer.Backlinks(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != len(expected) {
+			t.Fatalf("expected %d backlinks, got %d", len(expected), len(backlinks))
+		}
+		for _, obj := range expected {
+			found := false
+			for _, backlink := range backlinks {
+				if backlink.From.Equals(obj.Cid()) {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expected backlink from %s", obj.Cid())
+			}
+		}
+	}
+
+	recording := meta.MustEncode(map[string]interface{}{"title": "recording"})
+	ern1 := meta.MustEncode(map[string]interface{}{"recording": recording.Cid()})
+	ern2 := meta.MustEncode(map[string]interface{}{"recordings": []*cid.Cid{recording.Cid()}})
+	for _, obj := range []*meta.Object{recording, ern1} {
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertBacklinks(recording.Cid(), ern1)
+
+	// check objects are indexed when a batch is committed
+	batch, err := store.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Put(ern2); err != nil {
+		t.Fatal(err)
+	}
+	assertBacklinks(recording.Cid(), ern1)
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	assertBacklinks(recording.Cid(), ern1, ern2)
+
+	// check deleted objects are removed from the index
+	if err := store.Delete(ern1.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	assertBacklinks(recording.Cid(), ern2)
+
+	// check IndexStore removes objects deleted from a store the indexer
+	// was not added to
+	other := meta.NewStore(datastore.NewMapDatastore())
+	if err := other.Put(recording); err != nil {
+		t.Fatal(err)
+	}
+	indexer, err = NewIndexer(db, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.IndexStore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertBacklinks(recording.Cid())
+}
@@ -0,0 +1,51 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package backlink
+
+import "github.com/meta-network/go-meta/migrate"
+
+// migrations is a set of database migrations to run on a SQLite3 database
+// to prepare it for indexing the links between META objects.
+var migrations = migrate.NewMigrations()
+
+func init() {
+	// migration 1 creates a table of the objects which have been indexed
+	// and a table of the links they contain, indexed by the linked object
+	migrations.Add(1, `
+CREATE TABLE object (
+	id text NOT NULL PRIMARY KEY
+);
+
+CREATE TABLE backlink (
+	-- from_id is the cid of the object containing the link
+	from_id text NOT NULL,
+
+	-- path is the slash separated path of the property containing the link
+	path text NOT NULL,
+
+	-- to_id is the cid of the linked object
+	to_id text NOT NULL
+);
+
+CREATE INDEX backlink_from_id_idx ON backlink (from_id);
+CREATE INDEX backlink_to_id_idx   ON backlink (to_id);
+`,
+	)
+}
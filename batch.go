@@ -0,0 +1,198 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// ErrBatchDone is returned when using a batch which has already been
+// committed or rolled back.
+var ErrBatchDone = errors.New("meta: batch has already been committed or rolled back")
+
+// Batch is a set of object writes which are applied to the store together
+// when committed, or discarded if rolled back.
+//
+// Objects put in a batch cannot be read from the store until the batch is
+// committed. It is safe to use a batch from multiple goroutines.
+//
+// Typical usage would be:
+//
+//   batch, err := store.Batch()
+//   if err != nil {
+//     return err
+//   }
+//   defer batch.Rollback()
+//   for _, obj := range objs {
+//     if err := batch.Put(obj); err != nil {
+//       return err
+//     }
+//   }
+//   return batch.Commit()
+//
+type Batch struct {
+	store *Store
+	batch datastore.Batch
+
+	// puts and deletes are the objects put in and deleted by the batch,
+	// used to update the store's cache and indexes once the batch is
+	// committed.
+	puts    []*Object
+	deletes []*cid.Cid
+
+	mtx  sync.Mutex
+	done bool
+}
+
+// Batch returns a new batch of writes to the store, using the underlying
+// datastore's batching support if it has any.
+func (s *Store) Batch() (*Batch, error) {
+	batch, err := s.datastoreBatch()
+	if err != nil {
+		return nil, err
+	}
+	return &Batch{store: s, batch: batch}, nil
+}
+
+// datastoreBatch returns a batch of writes to the underlying datastore.
+func (s *Store) datastoreBatch() (datastore.Batch, error) {
+	if ds, ok := s.store.(datastore.Batching); ok {
+		return ds.Batch()
+	}
+	return datastore.NewBasicBatch(s.store), nil
+}
+
+// TxnDatastore is implemented by datastores which can run a function in a
+// transaction which is isolated from other writers to the datastore,
+// including those in other processes (e.g. the sqlite datastore).
+type TxnDatastore interface {
+	// Txn runs fn with a datastore which reads and writes within a
+	// transaction, committing the transaction if fn returns nil and
+	// rolling it back otherwise.
+	Txn(fn func(datastore.Datastore) error) error
+}
+
+// txn runs fn with a datastore whose writes are applied together once fn
+// returns nil.
+//
+// If the underlying datastore is a TxnDatastore then fn runs in a
+// transaction. Otherwise fn reads directly from the datastore and its writes
+// are applied in a single batch, so fn's reads are only isolated from writers
+// which hold a lock shared with the caller.
+func (s *Store) txn(fn func(datastore.Datastore) error) error {
+	if ds, ok := s.store.(TxnDatastore); ok {
+		return ds.Txn(fn)
+	}
+	batch, err := s.datastoreBatch()
+	if err != nil {
+		return err
+	}
+	if err := fn(&batchDatastore{Datastore: s.store, batch: batch}); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+// batchDatastore is a datastore which reads from an underlying datastore
+// but adds writes to a batch.
+type batchDatastore struct {
+	datastore.Datastore
+	batch datastore.Batch
+}
+
+func (b *batchDatastore) Put(key datastore.Key, value interface{}) error {
+	return b.batch.Put(key, value)
+}
+
+func (b *batchDatastore) Delete(key datastore.Key) error {
+	return b.batch.Delete(key)
+}
+
+// Put adds an object to the batch, along with its type index entry.
+func (b *Batch) Put(obj *Object) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.done {
+		return ErrBatchDone
+	}
+	if err := b.store.putObject(b.batch, obj); err != nil {
+		return err
+	}
+	b.puts = append(b.puts, obj)
+	return nil
+}
+
+// Delete adds the deletion of the object with the given CID to the batch.
+func (b *Batch) Delete(id *cid.Cid) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.done {
+		return ErrBatchDone
+	}
+	keys, err := b.store.typeIndexKeys(id)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := b.batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	if err := b.batch.Delete(b.store.key(id)); err != nil {
+		return err
+	}
+	b.deletes = append(b.deletes, id)
+	return nil
+}
+
+// Commit applies the writes in the batch to the store, evicts deleted
+// objects from the store's cache and then updates the store's indexes.
+func (b *Batch) Commit() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.done {
+		return ErrBatchDone
+	}
+	b.done = true
+	b.store.gcMtx.RLock()
+	defer b.store.gcMtx.RUnlock()
+	if err := b.batch.Commit(); err != nil {
+		return err
+	}
+	for _, id := range b.deletes {
+		b.store.cache.remove(id)
+	}
+	return b.store.updateIndexes(b.puts, b.deletes)
+}
+
+// Rollback discards the writes in the batch, doing nothing if the batch has
+// already been committed so that it can be deferred.
+func (b *Batch) Rollback() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.done = true
+	b.batch = nil
+	b.puts = nil
+	b.deletes = nil
+}
@@ -0,0 +1,138 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestBatch(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+
+	// put objects in a batch from multiple goroutines
+	batch, err := store.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := make([]*Object, 10)
+	for i := range objs {
+		objs[i] = MustEncode(map[string]string{"@type": "Work", "title": fmt.Sprintf("work %d", i)})
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(objs))
+	for _, obj := range objs {
+		wg.Add(1)
+		go func(obj *Object) {
+			defer wg.Done()
+			errs <- batch.Put(obj)
+		}(obj)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// check the objects are only stored once the batch is committed
+	if has, err := store.Has(objs[0].Cid()); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatal("expected object to not be stored before commit")
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if _, err := store.Get(obj.Cid()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ids, err := store.FindByType("Work", 0, 0); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != len(objs) {
+		t.Fatalf("expected %d objects in the type index, got %d", len(objs), len(ids))
+	}
+	if err := batch.Put(objs[0]); err != ErrBatchDone {
+		t.Fatalf("expected ErrBatchDone, got %v", err)
+	}
+
+	// check a rolled back batch is not stored
+	batch, err = store.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := MustEncode(map[string]string{"title": "rolled back"})
+	if err := batch.Put(obj); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Delete(objs[0].Cid()); err != nil {
+		t.Fatal(err)
+	}
+	batch.Rollback()
+	if err := batch.Commit(); err != ErrBatchDone {
+		t.Fatalf("expected ErrBatchDone, got %v", err)
+	}
+	if has, err := store.Has(obj.Cid()); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatal("expected rolled back object to not be stored")
+	}
+	if has, err := store.Has(objs[0].Cid()); err != nil {
+		t.Fatal(err)
+	} else if !has {
+		t.Fatal("expected rolled back delete to not be applied")
+	}
+}
+
+// TestBatchDeleteCache tests that an object deleted in a batch is only
+// evicted from the store's cache once the batch is committed.
+func TestBatchDeleteCache(t *testing.T) {
+	store := NewCachedStore(datastore.NewMapDatastore(), 10)
+	obj := MustEncode(map[string]string{"title": "cached"})
+	if err := store.Put(obj); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(obj.Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := store.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Delete(obj.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if store.cache.get(obj.Cid()) == nil {
+		t.Fatal("expected object to be cached before commit")
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(obj.Cid()); err != datastore.ErrNotFound {
+		t.Fatalf("expected deleted object to not be found, got %v", err)
+	}
+}
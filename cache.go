@@ -0,0 +1,117 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+)
+
+// objectCache is a size bounded cache of decoded objects which evicts the
+// least recently used object when full.
+//
+// Objects are immutable and identified by the hash of their content, so
+// cached objects never need to be invalidated, only removed when deleted from
+// the store.
+//
+// A nil *objectCache is a valid cache which caches nothing.
+type objectCache struct {
+	size int
+
+	mtx     sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// newObjectCache returns a cache which holds at most size objects, or nil if
+// size is not positive.
+func newObjectCache(size int) *objectCache {
+	if size <= 0 {
+		return nil
+	}
+	return &objectCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the cached object with the given CID, or nil if it is not
+// cached.
+func (c *objectCache) get(id *cid.Cid) *Object {
+	if c == nil {
+		return nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	elem, ok := c.entries[id.KeyString()]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*Object)
+}
+
+// add adds an object to the cache, evicting the least recently used object
+// if the cache is full.
+func (c *objectCache) add(obj *Object) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	key := obj.Cid().KeyString()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(obj)
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*Object).Cid().KeyString())
+	}
+}
+
+// remove removes the object with the given CID from the cache.
+func (c *objectCache) remove(id *cid.Cid) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	key := id.KeyString()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// len returns the number of cached objects.
+func (c *objectCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.lru.Len()
+}
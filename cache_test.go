@@ -0,0 +1,79 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestCachedStore(t *testing.T) {
+	ds := datastore.NewMapDatastore()
+	store := NewCachedStore(ds, 2)
+	objs := []*Object{
+		MustEncode(map[string]string{"name": "one"}),
+		MustEncode(map[string]string{"name": "two"}),
+		MustEncode(map[string]string{"name": "three"}),
+	}
+	for _, obj := range objs {
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// check getting objects caches them up to the cache size
+	for _, obj := range objs {
+		if _, err := store.Get(obj.Cid()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := store.cache.len(); n != 2 {
+		t.Fatalf("expected 2 cached objects, got %d", n)
+	}
+
+	// check cached objects are returned without reading the datastore,
+	// and the least recently used object was evicted
+	for _, obj := range objs {
+		if err := ds.Delete(store.key(obj.Cid())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, expected := range []bool{false, true, true} {
+		obj, err := store.Get(objs[i].Cid())
+		if cached := err == nil; cached != expected {
+			t.Fatalf("expected object %d cached to be %t, got %t", i, expected, cached)
+		}
+		if err == nil && obj != store.cache.get(objs[i].Cid()) {
+			t.Fatalf("expected object %d to be returned from the cache", i)
+		}
+	}
+
+	// check deleting an object removes it from the cache
+	if err := ds.Put(store.key(objs[2].Cid()), objs[2].RawData()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(objs[2].Cid()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(objs[2].Cid()); err != datastore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	"github.com/lmars/go-ipld-cbor"
+)
+
+// carVersion is the version of the Content Addressable aRchive format which
+// is read and written (see https://github.com/ipld/specs/blob/master/block-layer/content-addressable-archives.md).
+const carVersion = 1
+
+// maxCARSectionSize is the maximum size of a CAR section which will be read,
+// protecting against allocating huge buffers when reading corrupt archives.
+const maxCARSectionSize = 32 * 1024 * 1024
+
+// ExportCAR writes the graph rooted at the object with the given CID to w as
+// a CARv1 archive, with the root object first followed by the objects
+// reachable from it in depth-first order.
+//
+// Links to objects which are not in the store are not followed.
+func ExportCAR(ctx context.Context, store *Store, root *cid.Cid, w io.Writer) error {
+	obj, err := store.Get(root)
+	if err != nil {
+		return err
+	}
+
+	header, err := cbornode.DumpObject(map[string]interface{}{
+		"roots":   []*cid.Cid{root},
+		"version": carVersion,
+	})
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := writeCARSection(bw, header); err != nil {
+		return err
+	}
+
+	err = NewGraph(store, obj).Walk(ctx, func(_ []string, obj *Object, _ int) error {
+		return writeCARSection(bw, obj.Cid().Bytes(), obj.RawData())
+	}, &WalkOptions{Order: DepthFirst, IgnoreMissing: true})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeCARSection writes the given data to w prefixed with its total length
+// as an unsigned varint.
+func writeCARSection(w io.Writer, data ...[]byte) error {
+	var size int
+	for _, d := range data {
+		size += len(d)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(size))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for _, d := range data {
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportCAR reads a CARv1 archive from r, verifying each block against its
+// CID and storing it as an object, and returns the archive's root CIDs.
+//
+// The objects are stored in a single batch which is only committed once the
+// whole archive has been read and verified, and an error is returned if any
+// of the header's roots is not one of the archive's blocks. Datastore batches
+// (including those of the sqlite datastore) hold their writes in memory
+// until committed, so the whole archive must fit in memory; larger graphs
+// should be imported as several archives.
+//
+// Only CIDv1 blocks, as written by ExportCAR, are supported.
+func ImportCAR(ctx context.Context, store *Store, r io.Reader) ([]*cid.Cid, error) {
+	br := bufio.NewReader(r)
+
+	// read the header
+	data, err := readCARSection(br)
+	if err == io.EOF {
+		return nil, fmt.Errorf("meta: invalid CAR archive: missing header")
+	} else if err != nil {
+		return nil, err
+	}
+	roots, err := decodeCARHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := store.Batch()
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Rollback()
+
+	// read each block, adding it to the batch
+	seen := make(map[string]bool)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := readCARSection(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		id, n, err := readCid(data)
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid CID in CAR archive: %s", err)
+		}
+		block, err := NewBlock(id, data[n:])
+		if err != nil {
+			return nil, err
+		}
+		obj, err := NewObjectFromBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.Put(obj); err != nil {
+			return nil, err
+		}
+		seen[id.KeyString()] = true
+	}
+
+	for _, root := range roots {
+		if !seen[root.KeyString()] {
+			return nil, fmt.Errorf("meta: invalid CAR archive: missing root %s", root)
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return nil, err
+	}
+	return roots, nil
+}
+
+// readCARSection reads a varint length prefixed section from r, returning
+// io.EOF if there are no more sections.
+func readCARSection(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("meta: invalid CAR section length: %s", err)
+	}
+	if size > maxCARSectionSize {
+		return nil, fmt.Errorf("meta: CAR section too large: %d bytes", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("meta: truncated CAR section: %s", err)
+	}
+	return data, nil
+}
+
+// readCid reads a binary CIDv1 from the start of data, returning the CID and
+// the number of bytes it occupies.
+func readCid(data []byte) (*cid.Cid, int, error) {
+	// CIDv1 is <version><codec><multihash code><digest length><digest>
+	// with all but the digest encoded as unsigned varints
+	var n int
+	var length uint64
+	for i := 0; i < 4; i++ {
+		v, vn := binary.Uvarint(data[n:])
+		if vn <= 0 {
+			return nil, 0, fmt.Errorf("invalid varint")
+		}
+		if i == 0 && v != 1 {
+			return nil, 0, fmt.Errorf("unsupported CID version %d", v)
+		}
+		n += vn
+		length = v
+	}
+	if uint64(len(data)-n) < length {
+		return nil, 0, fmt.Errorf("truncated multihash")
+	}
+	n += int(length)
+	id, err := cid.Cast(data[:n])
+	return id, n, err
+}
+
+// decodeCARHeader decodes a CARv1 header, returning its roots.
+func decodeCARHeader(data []byte) ([]*cid.Cid, error) {
+	node, err := cbornode.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("meta: invalid CAR header: %s", err)
+	}
+	version, _, err := node.Resolve([]string{"version"})
+	if err != nil {
+		return nil, fmt.Errorf("meta: invalid CAR header: %s", err)
+	}
+	if v, ok := version.(uint64); !ok || v != carVersion {
+		return nil, fmt.Errorf("meta: unsupported CAR version: %v", version)
+	}
+	v, _, err := node.Resolve([]string{"roots"})
+	if err != nil {
+		return nil, fmt.Errorf("meta: invalid CAR header: %s", err)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("meta: invalid CAR header: roots has type %T", v)
+	}
+	roots := make([]*cid.Cid, len(list))
+	for i, x := range list {
+		id, ok := x.(*cid.Cid)
+		if !ok {
+			return nil, fmt.Errorf("meta: invalid CAR header: root has type %T", x)
+		}
+		roots[i] = id
+	}
+	return roots, nil
+}
@@ -0,0 +1,125 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/lmars/go-ipld-cbor"
+)
+
+func TestCAR(t *testing.T) {
+	graph := testGraph(t)
+	root := graph.Root().Cid()
+
+	// export the graph
+	var buf bytes.Buffer
+	if err := ExportCAR(context.Background(), graph.store, root, &buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// import it into an empty store
+	store := NewStore(datastore.NewMapDatastore())
+	roots, err := ImportCAR(context.Background(), store, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || !roots[0].Equals(root) {
+		t.Fatalf("unexpected roots: %v", roots)
+	}
+
+	// check every object in the original graph was imported
+	stat, err := store.Stat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Count != 4 {
+		t.Fatalf("expected 4 objects to be imported, got %d", stat.Count)
+	}
+	v, err := NewGraph(store, graph.Root()).Get("children", "1", "children", "0", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "grandchild" {
+		t.Fatalf("unexpected value: %v", v)
+	}
+
+	// check a corrupt block fails verification
+	corrupt := make([]byte, len(data))
+	copy(corrupt, data)
+	corrupt[len(corrupt)-1] ^= 0xff
+	store = NewStore(datastore.NewMapDatastore())
+	if _, err := ImportCAR(context.Background(), store, bytes.NewReader(corrupt)); err == nil {
+		t.Fatal("expected error importing corrupt CAR archive")
+	}
+	if stat, err := store.Stat(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if stat.Count != 0 {
+		t.Fatalf("expected no objects to be imported from a corrupt archive, got %d", stat.Count)
+	}
+
+	// check a truncated archive fails
+	if _, err := ImportCAR(context.Background(), store, bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("expected error importing truncated CAR archive")
+	}
+	// check an archive which does not contain its root fails
+	missing := MustEncode(map[string]string{"name": "missing"})
+	header, err := cbornode.DumpObject(map[string]interface{}{
+		"roots":   []*cid.Cid{missing.Cid()},
+		"version": carVersion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := writeCARSection(&buf, header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCARSection(&buf, graph.Root().Cid().Bytes(), graph.Root().RawData()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportCAR(context.Background(), store, &buf); err == nil {
+		t.Fatal("expected error importing CAR archive without its root")
+	}
+
+	// check an archive containing a CIDv0 block fails
+	header, err = cbornode.DumpObject(map[string]interface{}{
+		"roots":   []*cid.Cid{root},
+		"version": carVersion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := writeCARSection(&buf, header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCARSection(&buf, cid.NewCidV0(root.Hash()).Bytes(), graph.Root().RawData()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportCAR(context.Background(), store, &buf); err == nil {
+		t.Fatal("expected error importing CAR archive with a CIDv0 block")
+	}
+}
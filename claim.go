@@ -0,0 +1,197 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/ipfs/go-cid"
+	"leb.io/hashland/keccakpg"
+)
+
+// ClaimType is the @type of a claim object.
+const ClaimType = "meta:claim"
+
+// Address identifies the signer of a claim, and is derived from a secp256k1
+// public key in the same way as an Ethereum address (the last 20 bytes of the
+// Keccak-256 hash of the public key).
+type Address [20]byte
+
+// ParseAddress parses a hex encoded address with an optional 0x prefix.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != len(a) {
+		return a, fmt.Errorf("meta: invalid address: %q", s)
+	}
+	copy(a[:], b)
+	return a, nil
+}
+
+// String returns the 0x prefixed hex encoding of the address.
+func (a Address) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// Bytes returns the address as a byte slice.
+func (a Address) Bytes() []byte {
+	return a[:]
+}
+
+// Key is a secp256k1 private key used to sign claims.
+type Key struct {
+	priv *btcec.PrivateKey
+}
+
+// GenerateKey generates a random key.
+func GenerateKey() (*Key, error) {
+	priv, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	return &Key{priv: priv}, nil
+}
+
+// NewKey returns the key with the given 32 byte private key.
+func NewKey(privateKey []byte) (*Key, error) {
+	if len(privateKey) != 32 {
+		return nil, fmt.Errorf("meta: invalid private key")
+	}
+	if d := new(big.Int).SetBytes(privateKey); d.Sign() == 0 || d.Cmp(btcec.S256().N) >= 0 {
+		return nil, fmt.Errorf("meta: invalid private key")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), privateKey)
+	return &Key{priv: priv}, nil
+}
+
+// PrivateKey returns the 32 byte private key.
+func (k *Key) PrivateKey() []byte {
+	return k.priv.Serialize()
+}
+
+// Address returns the address of the key.
+func (k *Key) Address() Address {
+	return pubkeyAddress(k.priv.PubKey())
+}
+
+// pubkeyAddress returns the address of the given public key.
+func pubkeyAddress(pub *btcec.PublicKey) Address {
+	var a Address
+	copy(a[:], keccak256(ecPublicKey(pub))[12:])
+	return a
+}
+
+// keccak256 returns the Keccak-256 hash of the given data.
+func keccak256(data ...[]byte) []byte {
+	h := keccakpg.New256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// Claim is an assertion by a signer about the object with the Subject CID.
+type Claim struct {
+	// Subject is the CID of the object being claimed.
+	Subject *cid.Cid
+
+	// Signer is the address of the key which signed the claim.
+	Signer Address
+
+	// Signature is the 65 byte [R || S || V] secp256k1 signature of the
+	// Keccak-256 hash of the subject CID bytes.
+	Signature []byte
+}
+
+// Sign returns a claim object of type meta:claim which contains the given
+// subject CID, the key's address and a signature of the subject by the key.
+func Sign(subject *cid.Cid, key *Key) (*Object, error) {
+	sig, err := ecSign(key.priv, keccak256(subject.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return Encode(map[string]interface{}{
+		"@type":     ClaimType,
+		"subject":   subject,
+		"signer":    key.Address().String(),
+		"signature": "0x" + hex.EncodeToString(sig),
+	})
+}
+
+// VerifyClaim decodes the given claim object and checks that its signature
+// of the subject was created by the signer, returning an error if not.
+func VerifyClaim(obj *Object) (*Claim, error) {
+	if obj.Type() != ClaimType {
+		return nil, fmt.Errorf("meta: expected object of type %s, got %q", ClaimType, obj.Type())
+	}
+	link, err := obj.GetLink("subject")
+	if err != nil {
+		return nil, err
+	}
+	signer, err := obj.GetString("signer")
+	if err != nil {
+		return nil, err
+	}
+	signature, err := obj.GetString("signature")
+	if err != nil {
+		return nil, err
+	}
+	claim := &Claim{Subject: link.Cid}
+	claim.Signer, err = ParseAddress(signer)
+	if err != nil {
+		return nil, err
+	}
+	claim.Signature, err = hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("meta: invalid claim signature: %s", err)
+	}
+	pub, err := ecRecover(keccak256(claim.Subject.Bytes()), claim.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if pubkeyAddress(pub) != claim.Signer {
+		return nil, fmt.Errorf("meta: claim of %s not signed by %s", claim.Subject, claim.Signer)
+	}
+	return claim, nil
+}
+
+// ResolveClaim returns the subject of obj and its verified claim if obj is a
+// claim, otherwise it returns obj and a nil claim.
+//
+// It is intended to be used by indexers which accept either objects or
+// claims about objects so that the signer can be recorded.
+func ResolveClaim(store *Store, obj *Object) (*Object, *Claim, error) {
+	if obj.Type() != ClaimType {
+		return obj, nil, nil
+	}
+	claim, err := VerifyClaim(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	subject, err := store.Get(claim.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subject, claim, nil
+}
@@ -0,0 +1,186 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestSecp256k1(t *testing.T) {
+	// check the address of private key 1
+	one := make([]byte, 32)
+	one[31] = 1
+	key, err := NewKey(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := key.Address().String(); addr != "0x7e5f4552091a69125d5dfcb7b8c2659029395bdf" {
+		t.Fatalf("unexpected address: %s", addr)
+	}
+
+	// check the RFC 6979 signature of SHA256("Satoshi Nakamoto") by
+	// private key 1
+	hash := sha256.Sum256([]byte("Satoshi Nakamoto"))
+	sig, err := ecSign(key.priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8" +
+		"2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5"
+	if hex.EncodeToString(sig[:64]) != expected {
+		t.Fatalf("unexpected signature: %x", sig[:64])
+	}
+
+	// check the public key is recovered from the signature
+	pub, err := ecRecover(hash[:], sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pubkeyAddress(pub) != key.Address() {
+		t.Fatalf("recovered wrong public key")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	subject := MustEncode(map[string]string{"name": "subject"})
+	if err := store.Put(subject); err != nil {
+		t.Fatal(err)
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sign and verify a claim
+	obj, err := Sign(subject.Cid(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Type() != ClaimType {
+		t.Fatalf("expected claim to have type %q, got %q", ClaimType, obj.Type())
+	}
+	claim, err := VerifyClaim(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claim.Subject.Equals(subject.Cid()) || claim.Signer != key.Address() {
+		t.Fatalf("unexpected claim: %+v", claim)
+	}
+
+	// check resolving the claim returns the subject
+	if err := store.Put(obj); err != nil {
+		t.Fatal(err)
+	}
+	resolved, claim, err := ResolveClaim(store, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resolved.Cid().Equals(subject.Cid()) || claim == nil {
+		t.Fatalf("unexpected resolved claim: %s %v", resolved.Cid(), claim)
+	}
+	if resolved, claim, err := ResolveClaim(store, subject); err != nil {
+		t.Fatal(err)
+	} else if resolved != subject || claim != nil {
+		t.Fatal("expected non-claim to resolve to itself")
+	}
+
+	// check a claim with a different signer fails verification
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := Encode(map[string]interface{}{
+		"@type":     ClaimType,
+		"subject":   subject.Cid(),
+		"signer":    other.Address().String(),
+		"signature": "0x" + hex.EncodeToString(claim.Signature),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyClaim(forged); err == nil {
+		t.Fatal("expected forged claim to fail verification")
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meta-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ks := NewKeyStore(dir)
+
+	key, err := ks.NewKey("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := ks.Addresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0] != key.Address() {
+		t.Fatalf("unexpected addresses: %v", addresses)
+	}
+	loaded, err := ks.Key(key.Address(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey(), key.PrivateKey()) {
+		t.Fatal("loaded key does not match generated key")
+	}
+	if _, err := ks.Key(key.Address(), "wrong"); err != ErrDecrypt {
+		t.Fatalf("expected ErrDecrypt, got %v", err)
+	}
+
+	// check key files with unexpected KDF parameters are rejected
+	data, err := encryptKey(key, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []struct{ c, dklen int }{
+		{1 << 40, keyStoreKeyLen},
+		{keyStoreIterations, 1 << 30},
+		{keyStoreIterations, 16},
+	} {
+		var f keyFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			t.Fatal(err)
+		}
+		f.Crypto.KDFParams.C = params.c
+		f.Crypto.KDFParams.DKLen = params.dklen
+		crafted, err := json.Marshal(&f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decryptKey(crafted, "secret"); err == nil {
+			t.Fatalf("expected error decrypting key file with c=%d dklen=%d", params.c, params.dklen)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+// The claimindex package provides the claim table shared by the CWR, ERN
+// and MusicBrainz indexes, which records the signers of claims about indexed
+// objects so that they can be filtered by trusted sources.
+package claimindex
+
+import (
+	"database/sql"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/mattn/go-sqlite3"
+	"github.com/meta-network/go-meta"
+)
+
+// Migration is the SQL migration which creates the claim table, and should
+// be added to the migrations of an index which records claims.
+const Migration = `
+CREATE TABLE claim (
+	-- object_id is the cid of the claimed object
+	object_id text NOT NULL,
+
+	-- signer is the address of the key which signed the claim
+	signer text NOT NULL
+);
+
+CREATE UNIQUE INDEX claim_unique_idx ON claim (object_id, signer);
+CREATE INDEX claim_signer_idx        ON claim (signer);
+`
+
+// Execer is implemented by both *sql.DB and *sql.Tx so that claims can be
+// indexed either directly or as part of a transaction.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Index records the signer of a claim, ignoring claims which have already
+// been indexed.
+func Index(db Execer, claim *meta.Claim) error {
+	log.Info("indexing claim", "subject", claim.Subject, "signer", claim.Signer)
+
+	_, err := db.Exec(
+		`INSERT INTO claim (object_id, signer) VALUES ($1, $2)`,
+		claim.Subject.String(), claim.Signer.String(),
+	)
+	if err != nil && !isUniqueErr(err) {
+		return err
+	}
+	return nil
+}
+
+// isUniqueErr determines whether an error is a SQLite3 uniqueness error.
+func isUniqueErr(err error) bool {
+	e, ok := err.(sqlite3.Error)
+	if !ok {
+		return false
+	}
+	return e.Code == sqlite3.ErrConstraint && e.ExtendedCode == sqlite3.ErrConstraintUnique
+}
@@ -0,0 +1,80 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package claimindex
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/migrate"
+)
+
+// TestIndex tests that indexing the same claim twice only records it once.
+func TestIndex(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "claimindex-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrations := migrate.NewMigrations()
+	migrations.Add(1, Migration)
+	if err := migrations.Run(db); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := meta.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject := meta.MustEncode(map[string]string{"name": "subject"})
+	obj, err := meta.Sign(subject.Cid(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := meta.VerifyClaim(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := Index(db, claim); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM claim WHERE object_id = $1 AND signer = $2`,
+		subject.Cid().String(), key.Address().String(),
+	).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 claim row, got %d", count)
+	}
+}
@@ -27,9 +27,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docopt/docopt-go"
 	"github.com/ethereum/go-ethereum/log"
@@ -37,6 +42,7 @@ import (
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/backlink"
 	"github.com/meta-network/go-meta/cwr"
 	"github.com/meta-network/go-meta/ern"
 	"github.com/meta-network/go-meta/musicbrainz"
@@ -44,26 +50,72 @@ import (
 )
 
 var usage = `
-usage: meta import xml <file> [<context>...]
+usage: meta import xml [--reversible] <file> [<context>...]
        meta import xsd <name> <uri> [<file>]
-       meta dump [--format=<format>] <path>
+       meta import car
+       meta export car <cid>
+       meta export rdf [--format=<format>] <cid>
+       meta export xml <cid>
+       meta dump [--format=<format>] [--depth=<depth>] [--expand] <path>
+       meta put
        meta server [--port=<port>] [--musicbrainz-index=<sqlite3-uri>] [--cwr-index=<sqlite3-uri>]
        meta musicbrainz convert <postgres-uri>
-       meta musicbrainz index <sqlite3-uri>
+       meta musicbrainz index [<sqlite3-uri>]
        meta cwr convert <files>...
-       meta cwr index <sqlite3-uri>
-       meta ern convert <files>...
-       meta ern index <sqlite3-uri>
+       meta cwr index [<sqlite3-uri>]
+       meta ern convert [--reversible] <files>...
+       meta ern index [<sqlite3-uri>]
+       meta pin add <name> <cid>
+       meta pin rm <name>
+       meta pin ls
+       meta ref set [--old=<old>] [--message=<message>] <name> <cid>
+       meta ref get <name>
+       meta ref ls
+       meta ref log <name>
+       meta log <cid>
+       meta gc [--dry-run]
+       meta fsck [--quarantine]
+       meta diff [--format=<format>] <old> <new>
+       meta prove <path>
+       meta refs-to [--reindex] <sqlite3-uri> <cid>
+       meta refs-to [--reindex] <cid>
+       meta find [--rebuild] --type=<type>
+       meta key new [--keystore=<dir>] [--passphrase-file=<file>]
+       meta key ls [--keystore=<dir>]
+       meta sign [--keystore=<dir>] [--passphrase-file=<file>] <address> <cid>
+       meta verify <cid>
+
+Every command uses the store given by a --store=<uri> flag anywhere in the
+arguments, the META_STORE environment variable or the store setting in the
+config file (see DefaultConfigPath), defaulting to fs://.meta. Supported
+store URIs are fs://<dir>, mem:// and sqlite://<path>.
+
+Index and server settings default to those in the config file.
+
+XML documents imported or converted with --reversible can be exported back
+to XML with 'meta export xml'.
+
+Key passphrases are read from the --passphrase-file flag or the
+META_PASSPHRASE environment variable, and must not be empty.
+
+The dump path can also be a selector containing wildcards, slices and
+predicates (e.g. <cid>/SoundRecording/*/SoundRecordingId/ISRC), in which
+case every matching value is dumped along with its path.
 `[1:]
 
 type CLI struct {
 	store  *meta.Store
+	config *Config
 	stdin  io.Reader
 	stdout io.Writer
 }
 
 func New(store *meta.Store, stdin io.Reader, stdout io.Writer) *CLI {
-	return &CLI{store, stdin, stdout}
+	return NewWithConfig(store, &Config{}, stdin, stdout)
+}
+
+func NewWithConfig(store *meta.Store, config *Config, stdin io.Reader, stdout io.Writer) *CLI {
+	return &CLI{store, config, stdin, stdout}
 }
 
 func (cli *CLI) Run(ctx context.Context, cmdArgs ...string) error {
@@ -73,8 +125,12 @@ func (cli *CLI) Run(ctx context.Context, cmdArgs ...string) error {
 	switch {
 	case args.Bool("import"):
 		return cli.RunImport(ctx, args)
+	case args.Bool("export"):
+		return cli.RunExport(ctx, args)
 	case args.Bool("dump"):
 		return cli.RunDump(ctx, args)
+	case args.Bool("put"):
+		return cli.RunPut(ctx, args)
 	case args.Bool("server"):
 		return cli.RunServer(ctx, args)
 	case args.Bool("musicbrainz"):
@@ -83,6 +139,30 @@ func (cli *CLI) Run(ctx context.Context, cmdArgs ...string) error {
 		return cli.RunCwr(ctx, args)
 	case args.Bool("ern"):
 		return cli.RunERN(ctx, args)
+	case args.Bool("pin"):
+		return cli.RunPin(ctx, args)
+	case args.Bool("ref"):
+		return cli.RunRef(ctx, args)
+	case args.Bool("gc"):
+		return cli.RunGC(ctx, args)
+	case args.Bool("fsck"):
+		return cli.RunFsck(ctx, args)
+	case args.Bool("diff"):
+		return cli.RunDiff(ctx, args)
+	case args.Bool("log"):
+		return cli.RunLog(ctx, args)
+	case args.Bool("prove"):
+		return cli.RunProve(ctx, args)
+	case args.Bool("refs-to"):
+		return cli.RunRefsTo(ctx, args)
+	case args.Bool("find"):
+		return cli.RunFind(ctx, args)
+	case args.Bool("key"):
+		return cli.RunKey(ctx, args)
+	case args.Bool("sign"):
+		return cli.RunSign(ctx, args)
+	case args.Bool("verify"):
+		return cli.RunVerify(ctx, args)
 	default:
 		return errors.New("unknown command")
 	}
@@ -94,6 +174,8 @@ func (cli *CLI) RunImport(ctx context.Context, args Args) error {
 		return cli.RunImportXML(ctx, args)
 	case args.Bool("xsd"):
 		return cli.RunImportXMLSchema(ctx, args)
+	case args.Bool("car"):
+		return cli.RunImportCAR(ctx, args)
 	default:
 		return errors.New("unknown import format")
 	}
@@ -117,10 +199,20 @@ func (cli *CLI) RunImportXML(ctx context.Context, args Args) error {
 		}
 	}
 
-	obj, err := metaxml.EncodeXML(f, context, cli.store.Put)
+	batch, err := cli.store.Batch()
+	if err != nil {
+		return err
+	}
+	defer batch.Rollback()
+	obj, err := metaxml.EncodeXML(f, context, batch.Put, &metaxml.EncodeXMLOptions{
+		Reversible: args.Bool("--reversible"),
+	})
 	if err != nil {
 		return err
 	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
 
 	log.Info("object created", "cid", obj.Cid())
 
@@ -163,8 +255,80 @@ func (cli *CLI) RunImportXMLSchema(ctx context.Context, args Args) error {
 	return nil
 }
 
+func (cli *CLI) RunImportCAR(ctx context.Context, args Args) error {
+	roots, err := meta.ImportCAR(ctx, cli.store, cli.stdin)
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		fmt.Fprintln(cli.stdout, root.String())
+	}
+	return nil
+}
+
+func (cli *CLI) RunExport(ctx context.Context, args Args) error {
+	switch {
+	case args.Bool("car"):
+		return cli.RunExportCAR(ctx, args)
+	case args.Bool("rdf"):
+		return cli.RunExportRDF(ctx, args)
+	case args.Bool("xml"):
+		return cli.RunExportXML(ctx, args)
+	default:
+		return errors.New("unknown export format")
+	}
+}
+
+func (cli *CLI) RunExportCAR(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	return meta.ExportCAR(ctx, cli.store, id, cli.stdout)
+}
+
+// RunExportRDF writes the graph of objects reachable from a CID as RDF in
+// either N-Quads (the default) or Turtle format.
+func (cli *CLI) RunExportRDF(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	format := meta.NQuads
+	if f := args.String("--format"); f != "" {
+		format = meta.RDFFormat(f)
+	}
+	return meta.ExportRDF(ctx, cli.store, id, cli.stdout, format)
+}
+
+// RunExportXML writes the XML document represented by a META object graph
+// which was created by 'meta import xml'.
+func (cli *CLI) RunExportXML(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	return metaxml.DecodeXML(cli.store, id, cli.stdout)
+}
+
 func (cli *CLI) RunDump(ctx context.Context, args Args) error {
-	path := strings.Split(args.String("<path>"), "/")
+	format := args.String("--format")
+	if format == "" {
+		format = "json"
+	}
+	writeFn, ok := dumpFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown dump format %q", format)
+	}
+	depth := 1
+	if d := args.String("--depth"); d != "" {
+		var err error
+		depth, err = strconv.Atoi(d)
+		if err != nil || depth < 0 {
+			return fmt.Errorf("invalid --depth value %q", d)
+		}
+	}
+	path := strings.SplitN(args.String("<path>"), "/", 2)
 	cid, err := cid.Decode(path[0])
 	if err != nil {
 		return err
@@ -173,21 +337,98 @@ func (cli *CLI) RunDump(ctx context.Context, args Args) error {
 	if err != nil {
 		return err
 	}
-	if len(path) == 1 {
-		return json.NewEncoder(cli.stdout).Encode(obj)
-	}
 	graph := meta.NewGraph(cli.store, obj)
-	v, err := graph.Get(path[1:]...)
+	if len(path) > 1 && isSelector(path[1]) {
+		return cli.dumpSelector(ctx, graph, path[1], format, args.Bool("--expand"))
+	}
+	var v interface{} = obj
+	if len(path) > 1 {
+		v, err = graph.Get(strings.Split(path[1], "/")...)
+		if err != nil {
+			return err
+		}
+	}
+	if args.Bool("--expand") {
+		switch format {
+		case "json", "yaml", "cbor":
+		default:
+			return fmt.Errorf("--expand is not supported by the %s format", format)
+		}
+		v, err = cli.expand(v)
+		if err != nil {
+			return err
+		}
+	}
+	return writeFn(ctx, cli.stdout, cli.store, v, depth)
+}
+
+// RunPut reads an object from stdin as IPLD DAG-JSON, stores it and prints
+// its CID.
+func (cli *CLI) RunPut(ctx context.Context, args Args) error {
+	obj, err := meta.DecodeJSON(cli.stdin)
 	if err != nil {
 		return err
 	}
-	return json.NewEncoder(cli.stdout).Encode(v)
+	if err := cli.store.Put(obj); err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, obj.Cid().String())
+	return nil
+}
+
+// isSelector returns whether a dump path is a selector (see
+// meta.Graph.Select) rather than an exact path.
+func isSelector(path string) bool {
+	return strings.ContainsAny(path, "*[")
+}
+
+// dumpMatch is a value matched by a selector passed to 'meta dump'.
+type dumpMatch struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// dumpSelector writes the values which match a selector as a list of
+// path and value pairs.
+func (cli *CLI) dumpSelector(ctx context.Context, graph *meta.Graph, selector, format string, expand bool) error {
+	switch format {
+	case "json", "yaml":
+	default:
+		return fmt.Errorf("selectors are not supported by the %s format", format)
+	}
+	matches, err := graph.Select(selector)
+	if err != nil {
+		return err
+	}
+	res := make([]*dumpMatch, len(matches))
+	for i, m := range matches {
+		v := m.Value
+		switch v.(type) {
+		case *meta.Object, *cid.Cid:
+			if expand {
+				if v, err = cli.expand(v); err != nil {
+					return err
+				}
+			}
+		}
+		res[i] = &dumpMatch{Path: strings.Join(m.Path, "/"), Value: v}
+	}
+	return dumpFormats[format](ctx, cli.stdout, cli.store, res, 0)
+}
+
+// expand expands the object which v refers to using its JSON-LD context.
+func (cli *CLI) expand(v interface{}) (interface{}, error) {
+	obj, err := graphObject(cli.store, v)
+	if err != nil {
+		return nil, err
+	}
+	return meta.Expand(cli.store, obj)
 }
 
 func (cli *CLI) RunServer(ctx context.Context, args Args) error {
 	var musicbrainzDB *sql.DB = nil
 	var cwrDB *sql.DB = nil
-	if uri := args.String("--musicbrainz-index"); uri != "" {
+	if uri := stringOr(args.String("--musicbrainz-index"), cli.config.Index.MusicBrainz); uri != "" {
 		db, err := sql.Open("sqlite3", uri)
 		if err != nil {
 			return err
@@ -195,7 +436,7 @@ func (cli *CLI) RunServer(ctx context.Context, args Args) error {
 		defer db.Close()
 		musicbrainzDB = db
 	}
-	if uri := args.String("--cwr-index"); uri != "" {
+	if uri := stringOr(args.String("--cwr-index"), cli.config.Index.CWR); uri != "" {
 		db, err := sql.Open("sqlite3", uri)
 		if err != nil {
 			return err
@@ -207,10 +448,7 @@ func (cli *CLI) RunServer(ctx context.Context, args Args) error {
 	if err != nil {
 		return err
 	}
-	port := args.String("--port")
-	if port == "" {
-		port = "5000"
-	}
+	port := stringOr(args.String("--port"), cli.config.Server.Port, "5000")
 	addr := "0.0.0.0:" + port
 	log.Info("starting META HTTP server", "addr", addr)
 	httpSrv := http.Server{
@@ -264,7 +502,7 @@ func (cli *CLI) RunMusicBrainzConvert(ctx context.Context, args Args) error {
 }
 
 func (cli *CLI) RunMusicBrainzIndex(ctx context.Context, args Args) error {
-	db, err := sql.Open("sqlite3", args.String("<sqlite3-uri>"))
+	db, err := cli.openIndex(args.String("<sqlite3-uri>"), cli.config.Index.MusicBrainz)
 	if err != nil {
 		return err
 	}
@@ -324,7 +562,7 @@ func (cli *CLI) RunCwrConvert(ctx context.Context, args Args) error {
 
 func (cli *CLI) RunCwrIndex(ctx context.Context, args Args) error {
 
-	db, err := sql.Open("sqlite3", args.String("<sqlite3-uri>"))
+	db, err := cli.openIndex(args.String("<sqlite3-uri>"), cli.config.Index.CWR)
 	if err != nil {
 		return err
 	}
@@ -366,6 +604,7 @@ func (cli *CLI) RunERN(ctx context.Context, args Args) error {
 
 func (cli *CLI) RunERNConvert(ctx context.Context, args Args) error {
 	converter := ern.NewConverter(cli.store)
+	converter.Reversible = args.Bool("--reversible")
 	files := args.List("<files>")
 	for _, file := range files {
 		f, err := os.Open(file)
@@ -383,7 +622,7 @@ func (cli *CLI) RunERNConvert(ctx context.Context, args Args) error {
 }
 
 func (cli *CLI) RunERNIndex(ctx context.Context, args Args) error {
-	db, err := sql.Open("sqlite3", args.String("<sqlite3-uri>"))
+	db, err := cli.openIndex(args.String("<sqlite3-uri>"), cli.config.Index.ERN)
 	if err != nil {
 		return err
 	}
@@ -412,6 +651,427 @@ func (cli *CLI) RunERNIndex(ctx context.Context, args Args) error {
 	return indexer.Index(ctx, stream)
 }
 
+func (cli *CLI) RunPin(ctx context.Context, args Args) error {
+	switch {
+	case args.Bool("add"):
+		return cli.RunPinAdd(ctx, args)
+	case args.Bool("rm"):
+		return cli.RunPinRm(ctx, args)
+	case args.Bool("ls"):
+		return cli.RunPinLs(ctx, args)
+	default:
+		return errors.New("unknown pin command")
+	}
+}
+
+func (cli *CLI) RunPinAdd(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	return cli.store.Pin(args.String("<name>"), id)
+}
+
+func (cli *CLI) RunPinRm(ctx context.Context, args Args) error {
+	return cli.store.Unpin(args.String("<name>"))
+}
+
+func (cli *CLI) RunPinLs(ctx context.Context, args Args) error {
+	pins, err := cli.store.Pins()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(cli.stdout, "%s %s\n", name, pins[name])
+	}
+	return nil
+}
+
+func (cli *CLI) RunRef(ctx context.Context, args Args) error {
+	switch {
+	case args.Bool("set"):
+		return cli.RunRefSet(ctx, args)
+	case args.Bool("get"):
+		return cli.RunRefGet(ctx, args)
+	case args.Bool("ls"):
+		return cli.RunRefLs(ctx, args)
+	case args.Bool("log"):
+		return cli.RunRefLog(ctx, args)
+	default:
+		return errors.New("unknown ref command")
+	}
+}
+
+func (cli *CLI) RunRefSet(ctx context.Context, args Args) error {
+	name := args.String("<name>")
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	message := args.String("--message")
+	old := args.String("--old")
+	if old == "" {
+		return cli.store.Refs().Set(name, id, message)
+	}
+	oldID, err := cid.Decode(old)
+	if err != nil {
+		return err
+	}
+	return cli.store.Refs().Update(name, oldID, id, message)
+}
+
+func (cli *CLI) RunRefGet(ctx context.Context, args Args) error {
+	id, err := cli.store.Refs().Get(args.String("<name>"))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, id.String())
+	return nil
+}
+
+func (cli *CLI) RunRefLs(ctx context.Context, args Args) error {
+	refs, err := cli.store.Refs().List()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(cli.stdout, "%s %s\n", name, refs[name])
+	}
+	return nil
+}
+
+func (cli *CLI) RunRefLog(ctx context.Context, args Args) error {
+	log, err := cli.store.Refs().Log(args.String("<name>"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range log {
+		fmt.Fprintf(cli.stdout, "%s %s %s\n", entry.Time.Format(time.RFC3339), entry.New, entry.Message)
+	}
+	return nil
+}
+
+func (cli *CLI) RunGC(ctx context.Context, args Args) error {
+	dryRun := args.Bool("--dry-run")
+	res, err := cli.store.GC(ctx, nil, &meta.GCOptions{DryRun: dryRun})
+	if err != nil {
+		return err
+	}
+	for _, id := range res.Removed {
+		fmt.Fprintln(cli.stdout, id.String())
+	}
+	if dryRun {
+		log.Info("garbage collection dry run", "objects", len(res.Removed), "bytes", res.Size)
+	} else {
+		log.Info("garbage collection complete", "objects", len(res.Removed), "bytes", res.Size)
+	}
+	return nil
+}
+
+func (cli *CLI) RunFsck(ctx context.Context, args Args) error {
+	res, err := cli.store.Verify(ctx, &meta.VerifyOptions{
+		Quarantine: args.Bool("--quarantine"),
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range res.Problems {
+		fmt.Fprintln(cli.stdout, p.String())
+	}
+	for _, id := range res.Quarantined {
+		log.Info("quarantined corrupt object", "cid", id)
+	}
+	log.Info("integrity check complete", "objects", res.Checked, "problems", len(res.Problems))
+	if len(res.Problems) > 0 {
+		return fmt.Errorf("found %d problems in %d objects", len(res.Problems), res.Checked)
+	}
+	return nil
+}
+
+func (cli *CLI) RunDiff(ctx context.Context, args Args) error {
+	format := args.String("--format")
+	if format == "" {
+		format = "text"
+	}
+	writeFn, ok := diffFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown diff format %q", format)
+	}
+	old, err := cid.Decode(args.String("<old>"))
+	if err != nil {
+		return err
+	}
+	new, err := cid.Decode(args.String("<new>"))
+	if err != nil {
+		return err
+	}
+	changes, err := meta.Diff(cli.store, old, new)
+	if err != nil {
+		return err
+	}
+	return writeFn(cli.stdout, changes)
+}
+
+func (cli *CLI) RunLog(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	history, err := meta.History(cli.store, id)
+	if err != nil {
+		return err
+	}
+	for _, obj := range history {
+		rev, err := obj.Revision()
+		if err != nil {
+			return err
+		}
+		if rev == nil {
+			fmt.Fprintln(cli.stdout, obj.Cid().String())
+			continue
+		}
+		fmt.Fprintf(cli.stdout, "%s %s %s %s\n", obj.Cid(), rev.Time.Format(time.RFC3339), rev.Author, rev.Reason)
+	}
+	return nil
+}
+
+func (cli *CLI) RunProve(ctx context.Context, args Args) error {
+	path := strings.Split(args.String("<path>"), "/")
+	id, err := cid.Decode(path[0])
+	if err != nil {
+		return err
+	}
+	proof, err := newProof(cli.store, id, path[1:])
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(cli.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(proof)
+}
+
+// proof is the JSON representation of a proof that a value is reachable
+// from a root CID, as written by 'meta prove' and the /proof HTTP endpoint.
+type proof struct {
+	Root   *cid.Cid    `json:"root"`
+	Path   []string    `json:"path"`
+	Value  interface{} `json:"value"`
+	Blocks [][]byte    `json:"blocks"`
+}
+
+// newProof returns a proof of the value at the given path from the object
+// with the given root CID.
+func newProof(store *meta.Store, root *cid.Cid, path []string) (*proof, error) {
+	obj, err := store.Get(root)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := meta.NewGraph(store, obj).Prove(path...)
+	if err != nil {
+		return nil, err
+	}
+	value, err := meta.VerifyProof(root, path, blocks)
+	if err != nil {
+		return nil, err
+	}
+	return &proof{
+		Root:   root,
+		Path:   path,
+		Value:  value,
+		Blocks: blocks,
+	}, nil
+}
+
+// RunRefsTo brings the backlink index up to date with the objects in the
+// store and then prints the CID and path of each link to the given CID.
+func (cli *CLI) RunRefsTo(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	db, err := cli.openIndex(args.String("<sqlite3-uri>"), cli.config.Index.Backlink)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	indexer, err := backlink.NewIndexer(db, cli.store)
+	if err != nil {
+		return err
+	}
+
+	// the configured index is kept up to date as objects are written to
+	// the store, so only index the whole store if requested (e.g. for a
+	// new index or one which has missed writes)
+	if args.Bool("--reindex") {
+		if err := indexer.IndexStore(ctx); err != nil {
+			return err
+		}
+	}
+	backlinks, err := indexer.Backlinks(id)
+	if err != nil {
+		return err
+	}
+	for _, link := range backlinks {
+		fmt.Fprintf(cli.stdout, "%s %s\n", link.From, strings.Join(link.Path, "/"))
+	}
+	return nil
+}
+
+func (cli *CLI) RunFind(ctx context.Context, args Args) error {
+	if args.Bool("--rebuild") {
+		if err := cli.store.RebuildTypeIndex(ctx); err != nil {
+			return err
+		}
+	}
+	ids, err := cli.store.FindByType(args.String("--type"), 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		fmt.Fprintln(cli.stdout, id.String())
+	}
+	return nil
+}
+
+func (cli *CLI) RunKey(ctx context.Context, args Args) error {
+	switch {
+	case args.Bool("new"):
+		return cli.RunKeyNew(ctx, args)
+	case args.Bool("ls"):
+		return cli.RunKeyLs(ctx, args)
+	default:
+		return errors.New("unknown key command")
+	}
+}
+
+func (cli *CLI) RunKeyNew(ctx context.Context, args Args) error {
+	passphrase, err := readPassphrase(args)
+	if err != nil {
+		return err
+	}
+	key, err := keyStore(args).NewKey(passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, key.Address().String())
+	return nil
+}
+
+func (cli *CLI) RunKeyLs(ctx context.Context, args Args) error {
+	addresses, err := keyStore(args).Addresses()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		fmt.Fprintln(cli.stdout, addr.String())
+	}
+	return nil
+}
+
+func (cli *CLI) RunSign(ctx context.Context, args Args) error {
+	address, err := meta.ParseAddress(args.String("<address>"))
+	if err != nil {
+		return err
+	}
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphrase(args)
+	if err != nil {
+		return err
+	}
+	key, err := keyStore(args).Key(address, passphrase)
+	if err != nil {
+		return err
+	}
+	claim, err := meta.Sign(id, key)
+	if err != nil {
+		return err
+	}
+	if err := cli.store.Put(claim); err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.stdout, claim.Cid().String())
+	return nil
+}
+
+func (cli *CLI) RunVerify(ctx context.Context, args Args) error {
+	id, err := cid.Decode(args.String("<cid>"))
+	if err != nil {
+		return err
+	}
+	obj, err := cli.store.Get(id)
+	if err != nil {
+		return err
+	}
+	claim, err := meta.VerifyClaim(obj)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cli.stdout, "%s %s\n", claim.Subject, claim.Signer)
+	return nil
+}
+
+// keyStore returns the key store in the directory given by the --keystore
+// flag, defaulting to ~/.meta/keystore.
+func keyStore(args Args) *meta.KeyStore {
+	dir := args.String("--keystore")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".meta", "keystore")
+	}
+	return meta.NewKeyStore(dir)
+}
+
+// readPassphrase reads a key passphrase from the file given by the
+// --passphrase-file flag or the META_PASSPHRASE environment variable,
+// returning an error if neither gives a non-empty passphrase so that keys are
+// never stored unencrypted.
+func readPassphrase(args Args) (string, error) {
+	passphrase := os.Getenv("META_PASSPHRASE")
+	if path := args.String("--passphrase-file"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	if passphrase == "" {
+		return "", errors.New("missing passphrase, either pass --passphrase-file or set META_PASSPHRASE")
+	}
+	return passphrase, nil
+}
+
+// openIndex opens the SQLite3 index with the given URI, falling back to the
+// given configured URI.
+func (cli *CLI) openIndex(uri, configured string) (*sql.DB, error) {
+	uri = stringOr(uri, configured)
+	if uri == "" {
+		return nil, errors.New("missing index URI, either pass it as an argument or set it in the config file")
+	}
+	return sql.Open("sqlite3", uri)
+}
+
+// stringOr returns the first of the given strings which is not empty.
+func stringOr(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 type Args map[string]interface{}
 
 func (a Args) String(name string) string {
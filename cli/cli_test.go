@@ -23,8 +23,13 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -142,6 +147,677 @@ func TestERNCommands(t *testing.T) {
 	}
 }
 
+// TestDumpCommand tests running the 'meta dump' command with the supported
+// output formats.
+func TestDumpCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	child := meta.MustEncode(map[string]interface{}{
+		"@type": "Person",
+		"name":  "child",
+	})
+	parent := meta.MustEncode(map[string]interface{}{
+		"@type":    "Person",
+		"name":     "parent",
+		"children": []*cid.Cid{child.Cid()},
+	})
+	for _, obj := range []*meta.Object{child, parent} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// check the cbor format outputs the raw object
+	out := c.run("dump", "--format=cbor", parent.Cid().String())
+	if !bytes.Equal([]byte(out), parent.RawData()) {
+		t.Fatalf("unexpected CBOR output: %x", out)
+	}
+
+	for format, expected := range map[string]string{
+		"json": `{"@type":"Person","children":[{"/":"` + child.Cid().String() + `"}],"name":"parent"}` + "\n",
+		"yaml": `"@type": "Person"
+children:
+  - "/": "` + child.Cid().String() + `"
+name: "parent"
+`,
+		"dot": `digraph "` + parent.Cid().String() + `" {
+  "` + parent.Cid().String() + `" [label="Person\n` + parent.Cid().String() + `"];
+  "` + parent.Cid().String() + `" -> "` + child.Cid().String() + `" [label="children/0"];
+  "` + child.Cid().String() + `" [label="Person\n` + child.Cid().String() + `"];
+}
+`,
+		"nquads": `<ipld:` + parent.Cid().String() + `> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <meta:Person> <ipld:` + parent.Cid().String() + `> .
+<ipld:` + parent.Cid().String() + `> <meta:children> <ipld:` + child.Cid().String() + `> <ipld:` + parent.Cid().String() + `> .
+<ipld:` + parent.Cid().String() + `> <meta:name> "parent" <ipld:` + parent.Cid().String() + `> .
+<ipld:` + child.Cid().String() + `> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <meta:Person> <ipld:` + parent.Cid().String() + `> .
+<ipld:` + child.Cid().String() + `> <meta:name> "child" <ipld:` + parent.Cid().String() + `> .
+`,
+	} {
+		out := c.run("dump", "--format="+format, parent.Cid().String())
+		if out != expected {
+			t.Fatalf("unexpected %s output:\nexpected: %q\ngot:      %q", format, expected, out)
+		}
+	}
+
+	// check dumping a path with a depth of zero only outputs a single
+	// object
+	out = c.run("dump", "--format=dot", "--depth=0", parent.Cid().String()+"/children/0")
+	if strings.Contains(out, "->") {
+		t.Fatalf("expected no edges with --depth=0, got:\n%s", out)
+	}
+
+	// check dumping a selector outputs each match with its path
+	out = c.run("dump", parent.Cid().String()+"/children/*[@type=Person]/name")
+	if expected := `[{"path":"children/0/name","value":"child"}]` + "\n"; out != expected {
+		t.Fatalf("unexpected selector output: %q", out)
+	}
+
+	// check YAML keys which would be read as booleans or null are quoted
+	// and backslashes and quotes are escaped in DOT labels
+	odd := meta.MustEncode(map[string]interface{}{
+		"@type": `C:\dir "x"`,
+		"null":  "x",
+		"yes":   true,
+	})
+	if err := c.store.Put(odd); err != nil {
+		t.Fatal(err)
+	}
+	out = c.run("dump", "--format=yaml", odd.Cid().String())
+	if expected := `"@type": "C:\\dir \"x\""
+"null": "x"
+"yes": true
+`; out != expected {
+		t.Fatalf("unexpected yaml output:\nexpected: %q\ngot:      %q", expected, out)
+	}
+	out = c.run("dump", "--format=dot", odd.Cid().String())
+	if label := `[label="C:\\dir \"x\"\n` + odd.Cid().String() + `"]`; !strings.Contains(out, label) {
+		t.Fatalf("expected dot output to contain %s, got:\n%s", label, out)
+	}
+}
+
+// TestDumpExpand tests running 'meta dump --expand' and requesting expanded
+// objects from the HTTP API.
+func TestDumpExpand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	context := meta.MustEncode(map[string]interface{}{
+		"@context": map[string]interface{}{
+			"schema": "http://schema.org/",
+			"Person": "schema:Person",
+			"name":   "schema:name",
+		},
+	})
+	person := meta.MustEncode(map[string]interface{}{
+		"@context": context.Cid(),
+		"@type":    "Person",
+		"name":     "Alice",
+	})
+	for _, obj := range []*meta.Object{context, person} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := `{"@type":"http://schema.org/Person","http://schema.org/name":"Alice"}` + "\n"
+	if out := c.run("dump", "--expand", person.Cid().String()); out != expected {
+		t.Fatalf("unexpected expanded output: %q", out)
+	}
+
+	srv, err := NewServer(c.store, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	res, err := http.Get(s.URL + "/object/" + person.Cid().String() + "?expand=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected HTTP status: %s", res.Status)
+	}
+	if string(body) != expected {
+		t.Fatalf("unexpected expanded HTTP response: %q", body)
+	}
+}
+
+// TestExportRDFCommand tests running the 'meta export rdf' command.
+func TestExportRDFCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	child := meta.MustEncode(map[string]interface{}{"name": "child"})
+	parent := meta.MustEncode(map[string]interface{}{"name": "parent", "child": child.Cid()})
+	for _, obj := range []*meta.Object{child, parent} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := "<ipld:" + parent.Cid().String() + ">"
+	expected := p + ` <meta:child> <ipld:` + child.Cid().String() + `> ` + p + ` .
+` + p + ` <meta:name> "parent" ` + p + ` .
+<ipld:` + child.Cid().String() + `> <meta:name> "child" ` + p + ` .
+`
+	if out := c.run("export", "rdf", parent.Cid().String()); out != expected {
+		t.Fatalf("unexpected N-Quads output:\n%s", out)
+	}
+	out := c.run("export", "rdf", "--format=turtle", parent.Cid().String())
+	if !strings.Contains(out, "\n"+p+"\n    <meta:child> <ipld:"+child.Cid().String()+"> ;\n") {
+		t.Fatalf("unexpected Turtle output:\n%s", out)
+	}
+}
+
+// TestExportXMLCommand tests running the 'meta export xml' command.
+func TestExportXMLCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	id := strings.TrimSpace(c.run("ern", "convert", "--reversible", "../ern/testdata/Profile_AudioSingle.xml"))
+	out := c.run("export", "xml", id)
+	if !strings.HasPrefix(out, xml.Header+"<ern:NewReleaseMessage ") {
+		t.Fatalf("unexpected XML output:\n%s", out)
+	}
+	if !strings.Contains(out, "\n    <MessageThreadId>THREAD01</MessageThreadId>\n") {
+		t.Fatalf("unexpected XML output:\n%s", out)
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	child := meta.MustEncode(map[string]interface{}{"name": "child"})
+	parent := meta.MustEncode(map[string]interface{}{
+		"name":     "parent",
+		"children": []*cid.Cid{child.Cid()},
+	})
+	for _, obj := range []*meta.Object{child, parent} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	graph, err := meta.NewGraph(c.store, parent).Set([]string{"children", "0", "name"}, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err = graph.Set([]string{"age"}, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := parent.Cid().String()
+	new := graph.Root().Cid().String()
+
+	for format, expected := range map[string]string{
+		"text": `+ /age: "42"
+~ /children/0/name: "child" -> "renamed"
+`,
+		"json": `[{"type":"added","path":["age"],"new":"42"},{"type":"changed","path":["children","0","name"],"old":"child","new":"renamed"}]` + "\n",
+	} {
+		out := c.run("diff", "--format="+format, old, new)
+		if out != expected {
+			t.Fatalf("unexpected %s output:\nexpected: %q\ngot:      %q", format, expected, out)
+		}
+	}
+
+	// check identical graphs have no changes
+	if out := c.run("diff", old, old); out != "" {
+		t.Fatalf("expected no changes, got %q", out)
+	}
+}
+
+func TestRefCommands(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	v1 := meta.MustEncode(map[string]interface{}{"name": "v1"})
+	v2 := meta.MustEncode(map[string]interface{}{"name": "v2"})
+	for _, obj := range []*meta.Object{v1, v2} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := "artists/latest"
+	c.run("ref", "set", "--message=create", name, v1.Cid().String())
+	c.run("ref", "set", "--old="+v1.Cid().String(), "--message=update", name, v2.Cid().String())
+
+	if out := c.run("ref", "get", name); out != v2.Cid().String()+"\n" {
+		t.Fatalf("unexpected ref get output: %q", out)
+	}
+	if out := c.run("ref", "ls"); out != name+" "+v2.Cid().String()+"\n" {
+		t.Fatalf("unexpected ref ls output: %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(c.run("ref", "log", name)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 ref log lines, got %d", len(lines))
+	}
+	if !strings.HasSuffix(lines[0], v2.Cid().String()+" update") {
+		t.Fatalf("unexpected ref log line: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], v1.Cid().String()+" create") {
+		t.Fatalf("unexpected ref log line: %q", lines[1])
+	}
+
+	// check the server resolves refs
+	srv, err := NewServer(c.store, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	for path, expected := range map[string]string{
+		"/ref/" + name:           `{"name":"v2"}` + "\n",
+		"/ref/" + name + "/name": `"v2"` + "\n",
+	} {
+		res, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected HTTP status for %s: %s", path, res.Status)
+		}
+		if string(body) != expected {
+			t.Fatalf("unexpected response for %s: %q", path, body)
+		}
+	}
+	res, err := http.Get(s.URL + "/ref/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected HTTP status 404 for missing ref, got %s", res.Status)
+	}
+}
+
+// TestProveCommand tests running the 'meta prove' command and requesting a
+// proof from the HTTP server.
+func TestProveCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	work := meta.MustEncode(map[string]interface{}{"iswc": "T-034.524.680-1"})
+	root := meta.MustEncode(map[string]interface{}{"works": []*cid.Cid{work.Cid()}})
+	for _, obj := range []*meta.Object{work, root} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := []string{"works", "0", "iswc"}
+
+	// checkProof decodes a proof and checks it proves the ISWC
+	checkProof := func(data []byte) {
+		var proof struct {
+			Blocks [][]byte `json:"blocks"`
+		}
+		if err := json.Unmarshal(data, &proof); err != nil {
+			t.Fatal(err)
+		}
+		v, err := meta.VerifyProof(root.Cid(), path, proof.Blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != "T-034.524.680-1" {
+			t.Fatalf("unexpected proved value: %v", v)
+		}
+	}
+
+	checkProof([]byte(c.run("prove", root.Cid().String()+"/"+strings.Join(path, "/"))))
+
+	srv, err := NewServer(c.store, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	res, err := http.Get(s.URL + "/proof/" + root.Cid().String() + "/" + strings.Join(path, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected HTTP status: %s", res.Status)
+	}
+	checkProof(body)
+}
+
+// TestPutCommand tests running the 'meta put' command and storing objects
+// using POST /object.
+func TestPutCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	work := meta.MustEncode(map[string]interface{}{"iswc": "T-034.524.680-1"})
+	if err := c.store.Put(work); err != nil {
+		t.Fatal(err)
+	}
+	root := meta.MustEncode(map[string]interface{}{"title": "Example", "work": work.Cid()})
+	data, err := json.Marshal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check 'meta put' stores the object with the same CID
+	out := c.runWithStdin(bytes.NewReader(data), "put")
+	if out != root.Cid().String()+"\n" {
+		t.Fatalf("unexpected put output: %q", out)
+	}
+	if v, err := meta.NewGraph(c.store, root).Get("work", "iswc"); err != nil {
+		t.Fatal(err)
+	} else if v != "T-034.524.680-1" {
+		t.Fatalf("unexpected ISWC: %v", v)
+	}
+
+	srv, err := NewServer(c.store, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	post := func(contentType string, body []byte, status int) *cid.Cid {
+		res, err := http.Post(s.URL+"/object", contentType, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != status {
+			t.Fatalf("unexpected HTTP status: %s", res.Status)
+		}
+		if status != http.StatusCreated {
+			return nil
+		}
+		var id cid.Cid
+		if err := json.NewDecoder(res.Body).Decode(&id); err != nil {
+			t.Fatal(err)
+		}
+		if loc := res.Header.Get("Location"); loc != "/object/"+id.String() {
+			t.Fatalf("unexpected Location header: %q", loc)
+		}
+		return &id
+	}
+	obj := meta.MustEncode(map[string]interface{}{"title": "Posted", "work": work.Cid()})
+	data, err = json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := post("application/json", data, http.StatusCreated); !id.Equals(obj.Cid()) {
+		t.Fatalf("expected CID %s, got %s", obj.Cid(), id)
+	}
+	obj = meta.MustEncode(map[string]interface{}{"title": "Posted CBOR"})
+	if id := post("application/cbor", obj.RawData(), http.StatusCreated); !id.Equals(obj.Cid()) {
+		t.Fatalf("expected CID %s, got %s", obj.Cid(), id)
+	}
+	if _, err := c.store.Get(obj.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	post("application/json", []byte(`{"a": {"/": "invalid"}}`), http.StatusBadRequest)
+
+	// check bodies larger than maxObjectSize are rejected
+	large := []byte(`{"a": "` + strings.Repeat("x", maxObjectSize) + `"}`)
+	post("application/json", large, http.StatusBadRequest)
+}
+
+// TestRefsToCommand tests running the 'meta refs-to' command.
+func TestRefsToCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	recording := meta.MustEncode(map[string]interface{}{"title": "recording"})
+	ern := meta.MustEncode(map[string]interface{}{"recordings": []*cid.Cid{recording.Cid()}})
+	for _, obj := range []*meta.Object{recording, ern} {
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := filepath.Join(c.tmpDir, "backlink.db")
+	expected := ern.Cid().String() + " recordings/0\n"
+	if out := c.run("refs-to", "--reindex", db, recording.Cid().String()); out != expected {
+		t.Fatalf("unexpected refs-to output: %q", out)
+	}
+
+	// check the configured index is used without reindexing, and is
+	// kept up to date as objects are put in the store
+	config := &Config{Index: IndexConfig{Backlink: filepath.Join(c.tmpDir, "configured.db")}}
+	if err := config.AddBacklinkIndex(c.store); err != nil {
+		t.Fatal(err)
+	}
+	other := meta.MustEncode(map[string]interface{}{"recording": recording.Cid()})
+	if err := c.store.Put(other); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	cli := NewWithConfig(c.store, config, nil, &stdout)
+	if err := cli.Run(context.Background(), "refs-to", recording.Cid().String()); err != nil {
+		t.Fatal(err)
+	}
+	if out := stdout.String(); out != other.Cid().String()+" recording\n" {
+		t.Fatalf("unexpected refs-to output: %q", out)
+	}
+}
+
+// TestFindCommand tests running the 'meta find' command and requesting
+// objects by type from the HTTP server.
+func TestFindCommand(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	var expected []string
+	for _, title := range []string{"one", "two", "three"} {
+		obj := meta.MustEncode(map[string]interface{}{"@type": "SoundRecording", "title": title})
+		if err := c.store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, obj.Cid().String())
+	}
+	sort.Strings(expected)
+	if out := c.run("find", "--type=SoundRecording"); out != strings.Join(expected, "\n")+"\n" {
+		t.Fatalf("unexpected find output: %q", out)
+	}
+	if out := c.run("find", "--rebuild", "--type=SoundRecording"); out != strings.Join(expected, "\n")+"\n" {
+		t.Fatalf("unexpected find output after rebuild: %q", out)
+	}
+
+	srv, err := NewServer(c.store, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(srv)
+	defer s.Close()
+	var ids []string
+	path := "/objects?type=SoundRecording&limit=2"
+	for path != "" {
+		res, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page struct {
+			Objects    []map[string]string `json:"objects"`
+			NextOffset *int                `json:"next_offset"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range page.Objects {
+			ids = append(ids, obj["/"])
+		}
+		path = ""
+		if page.NextOffset != nil {
+			path = fmt.Sprintf("/objects?type=SoundRecording&limit=2&offset=%d", *page.NextOffset)
+		}
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("unexpected objects:\nexpected: %v\nactual:   %v", expected, ids)
+	}
+}
+
+// TestConfig tests loading a config file, choosing a store and using the
+// configured index paths.
+func TestConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "meta-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// check a missing config file results in an empty config
+	config, err := LoadConfig(filepath.Join(tmpDir, "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config, &Config{}) {
+		t.Fatalf("expected empty config, got %+v", config)
+	}
+
+	// check the store URI precedence
+	storePath := filepath.Join(tmpDir, "meta.db")
+	cwrIndex := filepath.Join(tmpDir, "cwr.db")
+	configPath := filepath.Join(tmpDir, "config.json")
+	data := []byte(fmt.Sprintf(`{"store":"sqlite://%s","index":{"cwr":%q}}`, storePath, cwrIndex))
+	if err := ioutil.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = LoadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("META_STORE", os.Getenv("META_STORE"))
+	os.Setenv("META_STORE", "mem://")
+	if uri := config.StoreURI("fs://flag"); uri != "fs://flag" {
+		t.Fatalf("expected flag to take precedence, got %q", uri)
+	}
+	if uri := config.StoreURI(""); uri != "mem://" {
+		t.Fatalf("expected META_STORE to take precedence, got %q", uri)
+	}
+	os.Setenv("META_STORE", "")
+	if uri := config.StoreURI(""); uri != "sqlite://"+storePath {
+		t.Fatalf("expected configured store, got %q", uri)
+	}
+	if uri := (&Config{}).StoreURI(""); uri != DefaultStoreURI {
+		t.Fatalf("expected default store, got %q", uri)
+	}
+
+	// check each store scheme can be opened
+	for _, uri := range []string{"fs://" + filepath.Join(tmpDir, "fs"), "mem://", config.StoreURI("")} {
+		store, err := OpenStore(uri)
+		if err != nil {
+			t.Fatalf("error opening %s: %s", uri, err)
+		}
+		obj := meta.MustEncode(map[string]string{"name": "test"})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(obj.Cid()); err != nil {
+			t.Fatalf("error getting object from %s: %s", uri, err)
+		}
+	}
+	if _, err := OpenStore("s3://bucket"); err == nil {
+		t.Fatal("expected unsupported store URI to fail")
+	}
+
+	// check the index commands use the configured index
+	store, err := OpenStore(config.StoreURI(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	ctx := context.Background()
+	cli := NewWithConfig(store, config, nil, &stdout)
+	if err := cli.Run(ctx, "cwr", "convert", "../cwr/testdata/example_nwr.cwr"); err != nil {
+		t.Fatal(err)
+	}
+	cli = NewWithConfig(store, config, strings.NewReader(stdout.String()), ioutil.Discard)
+	if err := cli.Run(ctx, "cwr", "index"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cwrIndex); err != nil {
+		t.Fatalf("expected configured CWR index to exist: %s", err)
+	}
+}
+
+// TestClaimCommands tests running the 'meta key', 'meta sign' and
+// 'meta verify' commands.
+func TestClaimCommands(t *testing.T) {
+	c, err := newTestCLI(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(c.tmpDir)
+
+	keystore := "--keystore=" + filepath.Join(c.tmpDir, "keystore")
+	passphraseFile := filepath.Join(c.tmpDir, "passphrase")
+	if err := ioutil.WriteFile(passphraseFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passphrase := "--passphrase-file=" + passphraseFile
+
+	// check a key isn't created without a passphrase
+	os.Unsetenv("META_PASSPHRASE")
+	cli := New(c.store, nil, ioutil.Discard)
+	if err := cli.Run(context.Background(), "key", "new", keystore); err == nil {
+		t.Fatal("expected an error creating a key without a passphrase")
+	}
+
+	address := strings.TrimSpace(c.run("key", "new", keystore, passphrase))
+	if out := c.run("key", "ls", keystore); out != address+"\n" {
+		t.Fatalf("unexpected key ls output: %q", out)
+	}
+
+	obj := meta.MustEncode(map[string]interface{}{"name": "subject"})
+	if err := c.store.Put(obj); err != nil {
+		t.Fatal(err)
+	}
+	claim := strings.TrimSpace(c.run("sign", keystore, passphrase, address, obj.Cid().String()))
+	if out := c.run("verify", claim); out != obj.Cid().String()+" "+address+"\n" {
+		t.Fatalf("unexpected verify output: %q", out)
+	}
+}
+
 type testCLI struct {
 	t      *testing.T
 	store  *meta.Store
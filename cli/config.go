@@ -0,0 +1,179 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/fs"
+	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/backlink"
+	"github.com/meta-network/go-meta/sqlite"
+)
+
+// DefaultStoreURI is the URI of the store used when none is configured.
+const DefaultStoreURI = "fs://.meta"
+
+// storeCacheSize is the number of recently used objects cached in memory by
+// stores opened with OpenStore.
+const storeCacheSize = 10000
+
+// Config is the configuration of the meta CLI which is read from a JSON
+// config file, for example:
+//
+//   {
+//     "store": "sqlite:///var/lib/meta/meta.db",
+//     "index": {
+//       "musicbrainz": "/var/lib/meta/musicbrainz.db",
+//       "cwr":         "/var/lib/meta/cwr.db",
+//       "ern":         "/var/lib/meta/ern.db",
+//       "backlink":    "/var/lib/meta/backlink.db"
+//     },
+//     "server": {
+//       "port": "5000"
+//     }
+//   }
+//
+type Config struct {
+	// Store is the URI of the store to use if neither the --store flag
+	// nor the META_STORE environment variable are set.
+	Store string `json:"store,omitempty"`
+
+	// Index contains the default SQLite3 URIs of the indexes.
+	Index IndexConfig `json:"index"`
+
+	// Server contains the default settings of 'meta server'.
+	Server ServerConfig `json:"server"`
+}
+
+// IndexConfig contains the default SQLite3 URIs of the indexes used by the
+// index commands and the HTTP server.
+type IndexConfig struct {
+	MusicBrainz string `json:"musicbrainz,omitempty"`
+	CWR         string `json:"cwr,omitempty"`
+	ERN         string `json:"ern,omitempty"`
+
+	// Backlink is the backlink index which, if set, is updated as
+	// objects are put in and deleted from the store by every command.
+	Backlink string `json:"backlink,omitempty"`
+}
+
+// ServerConfig contains the default settings of the HTTP server.
+type ServerConfig struct {
+	Port string `json:"port,omitempty"`
+}
+
+// DefaultConfigPath returns the path of the config file, which is
+// $XDG_CONFIG_HOME/meta/config.json, with XDG_CONFIG_HOME defaulting to
+// ~/.config.
+func DefaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "meta", "config.json")
+}
+
+// LoadConfig loads the config file at the given path, returning an empty
+// config if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error loading config file %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// StoreURI returns the URI of the store to use, which is the given flag
+// value, the META_STORE environment variable, the configured store or
+// DefaultStoreURI, whichever is set first.
+func (c *Config) StoreURI(flag string) string {
+	for _, uri := range []string{flag, os.Getenv("META_STORE"), c.Store} {
+		if uri != "" {
+			return uri
+		}
+	}
+	return DefaultStoreURI
+}
+
+// OpenStore opens the store with the given URI, which has one of the
+// following schemes:
+//
+//   fs://<dir>       - a FS datastore in the given directory
+//   mem://           - an in-memory datastore
+//   sqlite://<path>  - a SQLite3 datastore in the given file
+//
+func OpenStore(uri string) (*meta.Store, error) {
+	switch {
+	case strings.HasPrefix(uri, "fs://"):
+		dir := strings.TrimPrefix(uri, "fs://")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		store, err := fs.NewDatastore(dir)
+		if err != nil {
+			return nil, err
+		}
+		return meta.NewCachedStore(store, storeCacheSize), nil
+	case strings.HasPrefix(uri, "mem://"):
+		return meta.NewCachedStore(datastore.NewMapDatastore(), storeCacheSize), nil
+	case strings.HasPrefix(uri, "sqlite://"):
+		store, err := sqlite.NewDatastore(strings.TrimPrefix(uri, "sqlite://"))
+		if err != nil {
+			return nil, err
+		}
+		return meta.NewCachedStore(store, storeCacheSize), nil
+	default:
+		return nil, fmt.Errorf("unsupported store URI %q, expected fs://, mem:// or sqlite://", uri)
+	}
+}
+
+// AddBacklinkIndex adds the configured backlink index to the given store so
+// that it is updated as objects are put and deleted, doing nothing if no
+// backlink index is configured.
+func (c *Config) AddBacklinkIndex(store *meta.Store) error {
+	if c.Index.Backlink == "" {
+		return nil
+	}
+	db, err := sql.Open("sqlite3", c.Index.Backlink)
+	if err != nil {
+		return err
+	}
+	indexer, err := backlink.NewIndexer(db, store)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	store.AddIndex(indexer)
+	return nil
+}
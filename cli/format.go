@@ -0,0 +1,329 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/lmars/go-ipld-cbor"
+	"github.com/meta-network/go-meta"
+)
+
+// dumpFormats are the output formats supported by 'meta dump'.
+var dumpFormats = map[string]func(ctx context.Context, w io.Writer, store *meta.Store, v interface{}, depth int) error{
+	"json":   writeJSON,
+	"cbor":   writeCBOR,
+	"yaml":   writeYAML,
+	"dot":    writeDOT,
+	"nquads": writeNQuads,
+	"turtle": writeTurtle,
+}
+
+// diffFormats are the output formats supported by 'meta diff'.
+var diffFormats = map[string]func(w io.Writer, changes []*meta.Change) error{
+	"text": writeDiffText,
+	"json": writeDiffJSON,
+}
+
+// writeJSON writes v to w as JSON.
+func writeJSON(_ context.Context, w io.Writer, _ *meta.Store, v interface{}, _ int) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// writeCBOR writes v to w as IPLD DAG CBOR, writing the raw bytes of v if it
+// is an object so that the output matches its CID.
+func writeCBOR(_ context.Context, w io.Writer, _ *meta.Store, v interface{}, _ int) error {
+	if obj, ok := v.(*meta.Object); ok {
+		_, err := w.Write(obj.RawData())
+		return err
+	}
+	data, err := cbornode.DumpObject(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeYAML writes v to w as a YAML document.
+func writeYAML(_ context.Context, w io.Writer, _ *meta.Store, v interface{}, _ int) error {
+	// round trip v through JSON so that objects and links have the
+	// same representation as the JSON output
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var x interface{}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	y := &yamlWriter{w: w}
+	y.writeValue(x, 0)
+	return y.err
+}
+
+// yamlWriter writes generic JSON values as block style YAML.
+type yamlWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (y *yamlWriter) printf(format string, args ...interface{}) {
+	if y.err != nil {
+		return
+	}
+	_, y.err = fmt.Fprintf(y.w, format, args...)
+}
+
+// writeValue writes v at the given indentation level, assuming the cursor is
+// at the position where the value should start.
+func (y *yamlWriter) writeValue(v interface{}, indent int) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			y.printf("{}\n")
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if i > 0 {
+				y.printf("%s", strings.Repeat("  ", indent))
+			}
+			y.printf("%s:", yamlKey(key))
+			y.writeNested(v[key], indent+1)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			y.printf("[]\n")
+			return
+		}
+		for i, x := range v {
+			if i > 0 {
+				y.printf("%s", strings.Repeat("  ", indent))
+			}
+			y.printf("- ")
+			y.writeValue(x, indent+1)
+		}
+	default:
+		y.printf("%s\n", yamlScalar(v))
+	}
+}
+
+// writeNested writes a value which follows a mapping key, starting
+// non-empty collections on a new line.
+func (y *yamlWriter) writeNested(v interface{}, indent int) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) > 0 {
+			y.printf("\n%s", strings.Repeat("  ", indent))
+			y.writeValue(v, indent)
+			return
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			y.printf("\n%s", strings.Repeat("  ", indent))
+			y.writeValue(v, indent)
+			return
+		}
+	}
+	y.printf(" ")
+	y.writeValue(v, indent)
+}
+
+// yamlPlainKey matches keys which can be written without quotes.
+var yamlPlainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+
+// yamlReservedKey matches plain keys which YAML parsers would read as
+// booleans or null rather than strings, so must be quoted.
+var yamlReservedKey = regexp.MustCompile(`^(?i:y|n|yes|no|true|false|on|off|null)$`)
+
+func yamlKey(key string) string {
+	if yamlPlainKey.MatchString(key) && !yamlReservedKey.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		// JSON strings are valid YAML double quoted scalars
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// graphObject resolves the object which a dumped value refers to so that it
+// can be used as the starting point of a graph output format.
+func graphObject(store *meta.Store, v interface{}) (*meta.Object, error) {
+	switch v := v.(type) {
+	case *meta.Object:
+		return v, nil
+	case *cid.Cid:
+		return store.Get(v)
+	default:
+		return nil, fmt.Errorf("path must resolve to an object, got %T", v)
+	}
+}
+
+// writeDOT writes the neighbourhood of the object which v refers to as a
+// Graphviz DOT digraph, following links up to the given depth.
+func writeDOT(ctx context.Context, w io.Writer, store *meta.Store, v interface{}, depth int) error {
+	root, err := graphObject(store, v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "digraph %q {\n", root.Cid().String()); err != nil {
+		return err
+	}
+	err = meta.NewGraph(store, root).Walk(ctx, func(_ []string, obj *meta.Object, d int) error {
+		label := dotEscape(obj.Cid().String())
+		if typ := obj.Type(); typ != "" {
+			label = dotEscape(typ) + `\n` + label
+		}
+		if _, err := fmt.Fprintf(w, "  %q [label=\"%s\"];\n", obj.Cid().String(), label); err != nil {
+			return err
+		}
+		if d >= depth {
+			return meta.SkipLinks
+		}
+		links, err := obj.Links()
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			_, err := fmt.Fprintf(w, "  %q -> %q [label=%q];\n", obj.Cid().String(), link.Cid.String(), strings.Join(link.Path, "/"))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &meta.WalkOptions{IgnoreMissing: true})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, "}")
+	return err
+}
+
+// dotEscape escapes backslashes and double quotes in a string which is part
+// of a DOT label, so that the label's \n line separators are the only
+// escape sequences which are interpreted.
+func dotEscape(s string) string {
+	return strings.Replace(strings.Replace(s, `\`, `\\`, -1), `"`, `\"`, -1)
+}
+
+// writeNQuads writes the object which v refers to, along with objects linked
+// up to the given depth, as RDF N-Quads placed in a graph named after the
+// root object (see meta.RDFWriter).
+func writeNQuads(ctx context.Context, w io.Writer, store *meta.Store, v interface{}, depth int) error {
+	return writeRDF(ctx, w, store, v, depth, meta.NQuads)
+}
+
+// writeTurtle writes the object which v refers to, along with objects
+// linked up to the given depth, as RDF Turtle (see meta.RDFWriter).
+func writeTurtle(ctx context.Context, w io.Writer, store *meta.Store, v interface{}, depth int) error {
+	return writeRDF(ctx, w, store, v, depth, meta.Turtle)
+}
+
+func writeRDF(ctx context.Context, w io.Writer, store *meta.Store, v interface{}, depth int, format meta.RDFFormat) error {
+	root, err := graphObject(store, v)
+	if err != nil {
+		return err
+	}
+	r, err := meta.NewRDFWriter(w, store, format, root.Cid())
+	if err != nil {
+		return err
+	}
+	err = meta.NewGraph(store, root).Walk(ctx, func(_ []string, obj *meta.Object, d int) error {
+		if err := r.WriteObject(obj); err != nil {
+			return err
+		}
+		if d >= depth {
+			return meta.SkipLinks
+		}
+		return nil
+	}, &meta.WalkOptions{IgnoreMissing: true})
+	if err != nil {
+		return err
+	}
+	return r.Flush()
+}
+
+// writeDiffText writes changes to w with one line per change, prefixed with
+// '+' for added, '-' for removed and '~' for changed values, for example:
+//
+//   ~ /children/0/name: "Jane" -> "Janet"
+//
+func writeDiffText(w io.Writer, changes []*meta.Change) error {
+	for _, c := range changes {
+		path := "/" + strings.Join(c.Path, "/")
+		var err error
+		switch c.Type {
+		case meta.Added:
+			_, err = fmt.Fprintf(w, "+ %s: %s\n", path, diffValue(c.New))
+		case meta.Removed:
+			_, err = fmt.Fprintf(w, "- %s: %s\n", path, diffValue(c.Old))
+		default:
+			_, err = fmt.Fprintf(w, "~ %s: %s -> %s\n", path, diffValue(c.Old), diffValue(c.New))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffValue returns the JSON representation of a changed value.
+func diffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// writeDiffJSON writes changes to w as a JSON array.
+func writeDiffJSON(w io.Writer, changes []*meta.Change) error {
+	if changes == nil {
+		changes = []*meta.Change{}
+	}
+	return json.NewEncoder(w).Encode(changes)
+}
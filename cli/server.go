@@ -23,7 +23,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/ipfs/go-cid"
@@ -45,6 +47,10 @@ func NewServer(store *meta.Store, musicbrainzDB *sql.DB, cwrDB *sql.DB) (*Server
 		store:  store,
 	}
 	srv.router.GET("/object/:cid", srv.HandleGetObject)
+	srv.router.POST("/object", srv.HandlePostObject)
+	srv.router.GET("/objects", srv.HandleGetObjects)
+	srv.router.GET("/ref/*path", srv.HandleGetRef)
+	srv.router.GET("/proof/:cid/*path", srv.HandleGetProof)
 	srv.router.POST("/import/xml", srv.HandleImportXML)
 	if musicbrainzDB != nil {
 		musicbrainzApi, err := musicbrainz.NewAPI(musicbrainzDB, store)
@@ -83,11 +89,21 @@ func (s *Server) HandleImportXML(w http.ResponseWriter, req *http.Request, _ htt
 		}
 	}
 
-	obj, err := metaxml.EncodeXML(req.Body, context, s.store.Put)
+	batch, err := s.store.Batch()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	defer batch.Rollback()
+	obj, err := metaxml.EncodeXML(req.Body, context, batch.Put, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := batch.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(obj)
@@ -106,6 +122,192 @@ func (s *Server) HandleGetObject(w http.ResponseWriter, req *http.Request, p htt
 		return
 	}
 
+	s.writeValue(w, req, obj)
+}
+
+// HandlePostObject handles a request to store the object in the request
+// body, which is decoded as raw CBOR if the Content-Type is application/cbor
+// and as IPLD DAG-JSON otherwise, and responds with the object's CID.
+//
+// Bodies larger than maxObjectSize are rejected.
+func (s *Server) HandlePostObject(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxObjectSize)
+	var obj *meta.Object
+	var err error
+	switch mediaType(req) {
+	case "application/cbor":
+		obj, err = meta.DecodeCBOR(req.Body)
+	default:
+		obj, err = meta.DecodeJSON(req.Body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.Put(obj); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/object/"+obj.Cid().String())
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(obj.Cid())
+}
+
+// maxObjectSize is the maximum size in bytes of an object request body.
+const maxObjectSize = 1 << 20
+
+// mediaType returns the media type of the request's Content-Type header.
+func mediaType(req *http.Request) string {
+	typ, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return typ
+}
+
+// defaultObjectsLimit and maxObjectsLimit are the default and maximum number
+// of CIDs returned by a request to /objects.
+const (
+	defaultObjectsLimit = 100
+	maxObjectsLimit     = 1000
+)
+
+// HandleGetObjects handles a request for /objects?type=<type> by returning
+// the CIDs of objects with the given type, paginated using the offset and
+// limit query parameters.
+//
+// The response includes the offset of the next page if there are more
+// objects.
+func (s *Server) HandleGetObjects(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	query := req.URL.Query()
+	typ := query.Get("type")
+	if typ == "" {
+		http.Error(w, "missing type parameter", http.StatusBadRequest)
+		return
+	}
+	offset, limit := 0, defaultObjectsLimit
+	for name, v := range map[string]*int{"offset": &offset, "limit": &limit} {
+		if q := query.Get(name); q != "" {
+			n, err := strconv.Atoi(q)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("invalid %s parameter %q", name, q), http.StatusBadRequest)
+				return
+			}
+			*v = n
+		}
+	}
+	if limit == 0 || limit > maxObjectsLimit {
+		limit = maxObjectsLimit
+	}
+
+	// get one more than the limit to determine if there is a next page
+	ids, err := s.store.FindByType(typ, offset, limit+1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := struct {
+		Objects    []*cid.Cid `json:"objects"`
+		NextOffset *int       `json:"next_offset,omitempty"`
+	}{
+		Objects: make([]*cid.Cid, 0, len(ids)),
+	}
+	if len(ids) > limit {
+		ids = ids[:limit]
+		next := offset + limit
+		res.NextOffset = &next
+	}
+	res.Objects = append(res.Objects, ids...)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// HandleGetRef handles a request for /ref/<name>/<path> by resolving the
+// longest prefix of the request path which is a ref name and then getting
+// the rest of the path from the object the ref points at.
+func (s *Server) HandleGetRef(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
+	parts := strings.Split(strings.Trim(p.ByName("path"), "/"), "/")
+	refs := s.store.Refs()
+	for i := len(parts); i > 0; i-- {
+		id, err := refs.Get(strings.Join(parts[:i], "/"))
+		if meta.IsRefNotFound(err) {
+			continue
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.writePath(w, req, id, parts[i:])
+		return
+	}
+	http.Error(w, fmt.Sprintf("ref not found: %s", p.ByName("path")), http.StatusNotFound)
+}
+
+// HandleGetProof handles a request for /proof/<cid>/<path> by writing a
+// proof that the value at the path is reachable from the given CID.
+func (s *Server) HandleGetProof(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
+	id, err := cid.Decode(p.ByName("cid"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid CID %q: %s", p.ByName("cid"), err), http.StatusBadRequest)
+		return
+	}
+	var path []string
+	if v := strings.Trim(p.ByName("path"), "/"); v != "" {
+		path = strings.Split(v, "/")
+	}
+	proof, err := newProof(s.store, id, path)
+	if meta.IsPathNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(obj)
+	json.NewEncoder(w).Encode(proof)
+}
+
+// writePath writes the value at the given path from the object with the
+// given CID as JSON, loading the object if the value is a link.
+func (s *Server) writePath(w http.ResponseWriter, req *http.Request, id *cid.Cid, path []string) {
+	obj, err := s.store.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var v interface{} = obj
+	if len(path) > 0 {
+		v, err = meta.NewGraph(s.store, obj).Get(path...)
+		if meta.IsPathNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if id, ok := v.(*cid.Cid); ok {
+			v, err = s.store.Get(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	s.writeValue(w, req, v)
+}
+
+// writeValue writes v as JSON, first expanding it using its JSON-LD context
+// if it is an object and the request has an expand=true query parameter.
+func (s *Server) writeValue(w http.ResponseWriter, req *http.Request, v interface{}) {
+	if obj, ok := v.(*meta.Object); ok && req.URL.Query().Get("expand") == "true" {
+		expanded, err := meta.Expand(s.store, obj)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		v = expanded
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
 }
@@ -23,21 +23,29 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ipfs/go-datastore/fs"
-	"github.com/meta-network/go-meta"
 	"github.com/meta-network/go-meta/cli"
 )
 
 func main() {
 	log.Root().SetHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(true)))
 
-	store, err := openStore()
+	config, err := cli.LoadConfig(cli.DefaultConfigPath())
+	if err != nil {
+		log.Crit("error loading meta config", "err", err)
+	}
+
+	storeURI, args := storeFlag(os.Args[1:])
+	store, err := cli.OpenStore(config.StoreURI(storeURI))
 	if err != nil {
 		log.Crit("error opening meta store", "err", err)
 	}
+	if err := config.AddBacklinkIndex(store); err != nil {
+		log.Crit("error opening backlink index", "err", err)
+	}
 
 	// shutdown gracefully on SIGINT or SIGTERM
 	ctx, cancel := context.WithCancel(context.Background())
@@ -49,19 +57,29 @@ func main() {
 		log.Info("received signal, exiting...")
 	}()
 
-	if err := cli.New(store, os.Stdin, os.Stdout).Run(ctx, os.Args[1:]...); err != nil {
+	if err := cli.NewWithConfig(store, config, os.Stdin, os.Stdout).Run(ctx, args...); err != nil {
 		log.Crit("error running meta command", "err", err)
 	}
 }
 
-func openStore() (*meta.Store, error) {
-	metaDir := ".meta"
-	if err := os.MkdirAll(metaDir, 0755); err != nil {
-		return nil, err
-	}
-	store, err := fs.NewDatastore(metaDir)
-	if err != nil {
-		return nil, err
+// storeFlag extracts a --store=<uri> (or --store <uri>) flag from any
+// position in the given command line arguments before a "--" terminator,
+// returning the URI and the remaining arguments.
+func storeFlag(args []string) (string, []string) {
+	var uri string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return uri, append(rest, args[i:]...)
+		case strings.HasPrefix(arg, "--store="):
+			uri = strings.TrimPrefix(arg, "--store=")
+		case arg == "--store" && i+1 < len(args):
+			uri = args[i+1]
+			i++
+		default:
+			rest = append(rest, arg)
+		}
 	}
-	return meta.NewStore(store), nil
+	return uri, rest
 }
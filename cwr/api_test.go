@@ -223,6 +223,89 @@ func testTxRecords(x *testIndex,
 	return nil
 }
 
+// TestTransmissionHeaderHistoryAPI tests querying the revision history of a
+// transmission header via the GraphQL API.
+func TestTransmissionHeaderHistoryAPI(t *testing.T) {
+	x, err := newTestIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.cleanup()
+
+	// revise the transmission header and point the index at the new
+	// revision
+	senderName := "JAAK EXAMPLE SENDER NAME"
+	var id string
+	if err := x.db.QueryRow("SELECT object_id FROM transmission_header WHERE sender_name = ?", senderName).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	prevID, err := cid.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, err := x.store.Get(prevID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := meta.Revise(x.store, prev, map[string]interface{}{"note": "revised"}, &meta.Revision{
+		Author: "alice",
+		Reason: "add note",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.db.Exec("UPDATE transmission_header SET object_id = ? WHERE object_id = ?", obj.Cid().String(), id); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newTestAPI(x.db, x.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	query := fmt.Sprintf(`{ transmission_header(sender_name:%q) { history { cid author reason } } }`, senderName)
+	data, _ := json.Marshal(map[string]string{"query": query})
+	res, err := http.Post(s.URL+"/graphql", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var r graphql.Response
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors in API response: %v", r.Errors)
+	}
+	type revision struct {
+		Cid    string  `json:"cid"`
+		Author *string `json:"author"`
+		Reason *string `json:"reason"`
+	}
+	var th struct {
+		TransmissionHeaders []struct {
+			History []revision `json:"history"`
+		} `json:"transmission_header"`
+	}
+	if err := json.Unmarshal(r.Data, &th); err != nil {
+		t.Fatal(err)
+	}
+	if len(th.TransmissionHeaders) != 1 {
+		t.Fatalf("expected 1 transmission header, got %d", len(th.TransmissionHeaders))
+	}
+	history := th.TransmissionHeaders[0].History
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(history))
+	}
+	if history[0].Cid != obj.Cid().String() || history[0].Author == nil || *history[0].Author != "alice" || *history[0].Reason != "add note" {
+		t.Fatalf("unexpected latest revision: %+v", history[0])
+	}
+	if history[1].Cid != prev.Cid().String() || history[1].Author != nil {
+		t.Fatalf("unexpected original revision: %+v", history[1])
+	}
+}
+
 func newTestAPI(db *sql.DB, store *meta.Store) (*httptest.Server, error) {
 	api, err := NewAPI(db, store)
 	if err != nil {
@@ -78,7 +78,15 @@ type Cwr struct {
 
 // ConvertCWR converts the given source CWR file into a META object graph and
 // returns the CID of the graph's root META object.
+//
+// The objects are written to the store in a single batch which is only
+// committed if the whole file is converted successfully.
 func (c *Converter) ConvertCWR(cwrFileReader io.Reader) (*cid.Cid, error) {
+	batch, err := c.store.Batch()
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Rollback()
 
 	jobs := make(chan recordJob)
 	results := make(chan objectResult)
@@ -100,7 +108,7 @@ func (c *Converter) ConvertCWR(cwrFileReader io.Reader) (*cid.Cid, error) {
 	for i := 0; i < concurrentWorkNum; i++ {
 		go func() {
 			defer wg.Done()
-			c.worker(jobs, results)
+			c.worker(batch, jobs, results)
 		}()
 	}
 
@@ -130,7 +138,6 @@ func (c *Converter) ConvertCWR(cwrFileReader io.Reader) (*cid.Cid, error) {
 		close(results)
 	}()
 
-	var err error
 	for v := range results {
 		if v.err != nil {
 			err = v.err //get the err and continue to drain the channel
@@ -183,7 +190,10 @@ func (c *Converter) ConvertCWR(cwrFileReader io.Reader) (*cid.Cid, error) {
 		return nil, err
 	}
 
-	if err := c.store.Put(obj); err != nil {
+	if err := batch.Put(obj); err != nil {
+		return nil, err
+	}
+	if err := batch.Commit(); err != nil {
 		return nil, err
 	}
 	return obj.Cid(), nil
@@ -234,7 +244,7 @@ func newRecord(line string) (*Record, error) {
 	return record, nil
 }
 
-func (c *Converter) worker(jobs <-chan recordJob, results chan<- objectResult) {
+func (c *Converter) worker(batch *meta.Batch, jobs <-chan recordJob, results chan<- objectResult) {
 	for job := range jobs {
 		if job.record.RecordType != "" {
 			obj, err := meta.Encode(job.record)
@@ -242,7 +252,7 @@ func (c *Converter) worker(jobs <-chan recordJob, results chan<- objectResult) {
 				results <- objectResult{nil, 0, err}
 				return
 			}
-			if err := c.store.Put(obj); err != nil {
+			if err := batch.Put(obj); err != nil {
 				results <- objectResult{nil, 0, err}
 				return
 			}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ipfs/go-cid"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/history"
 )
 
 // GraphQLSchema is the GraphQL schema for the MusicBrainz META index.
@@ -60,12 +61,14 @@ type TransmissionHeader {
 	sender_type:              String!
 	sender_id:                String!
 	sender_name:              String!
+	history:                  [Revision!]!
 }
 
 type PublisherControl {
 	cid:                      String!
 	record_type:              String!
 	publisher_sequence_n:     String!
+	history:                  [Revision!]!
 }
 
 type RegisteredWork {
@@ -94,8 +97,9 @@ type RegisteredWork {
 	opus_number:              String!
 	catalogue_number:         String!
 	priority_flag:            String!
+	history:                  [Revision!]!
 }
-`
+` + history.RevisionSchema
 
 // Resolver defines GraphQL resolver functions for the schema contained in
 // the GraphQLSchema constant, retrieving data from a META store and SQLite3
@@ -171,7 +175,7 @@ func (g *Resolver) RegisteredWork(args registeredWorkArgs) ([]*registeredWorkRes
 		if err := obj.Decode(&registeredWork); err != nil {
 			return nil, err
 		}
-		resolvers = append(resolvers, &registeredWorkResolver{objectID, &registeredWork})
+		resolvers = append(resolvers, &registeredWorkResolver{objectID, &registeredWork, g.store})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -215,7 +219,7 @@ func (g *Resolver) PublisherControl(args publisherControlArgs) ([]*publisherCont
 		if err := obj.Decode(&publisherControllBySubmitter); err != nil {
 			return nil, err
 		}
-		resolvers = append(resolvers, &publisherControlResolver{objectID, &publisherControllBySubmitter})
+		resolvers = append(resolvers, &publisherControlResolver{objectID, &publisherControllBySubmitter, g.store})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -263,7 +267,7 @@ func (g *Resolver) TransmissionHeader(args transmissionHeaderArgs) ([]*transmiss
 		if err := obj.Decode(&transmissionHeader); err != nil {
 			return nil, err
 		}
-		resolvers = append(resolvers, &transmissionHeaderResolver{objectID, &transmissionHeader})
+		resolvers = append(resolvers, &transmissionHeaderResolver{objectID, &transmissionHeader, g.store})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -275,6 +279,7 @@ func (g *Resolver) TransmissionHeader(args transmissionHeaderArgs) ([]*transmiss
 type transmissionHeaderResolver struct {
 	cid                string
 	transmissionHeader *TransmissionHeader
+	store              *meta.Store
 }
 
 func (t *transmissionHeaderResolver) Cid() string {
@@ -297,10 +302,15 @@ func (t *transmissionHeaderResolver) SenderName() string {
 	return t.transmissionHeader.SenderName
 }
 
+func (t *transmissionHeaderResolver) History() ([]*history.RevisionResolver, error) {
+	return history.Resolvers(t.store, t.cid)
+}
+
 // publisherControlResolver defines GraphQL resolver functions for publisherControl fields.
 type publisherControlResolver struct {
 	cid              string
 	publisherControl *PublisherControllBySubmitter
+	store            *meta.Store
 }
 
 func (p *publisherControlResolver) Cid() string {
@@ -315,10 +325,15 @@ func (p *publisherControlResolver) PublisherSequenceN() string {
 	return p.publisherControl.PublisherSequenceNumber
 }
 
+func (p *publisherControlResolver) History() ([]*history.RevisionResolver, error) {
+	return history.Resolvers(p.store, p.cid)
+}
+
 // registeredWorkResolver defines GraphQL resolver functions for registeredWork fields.
 type registeredWorkResolver struct {
 	cid            string
 	registeredWork *RegisteredWork
+	store          *meta.Store
 }
 
 func (r *registeredWorkResolver) Cid() string {
@@ -416,3 +431,7 @@ func (r *registeredWorkResolver) VersionType() string {
 func (r *registeredWorkResolver) WorkType() string {
 	return r.registeredWork.WorkType
 }
+
+func (r *registeredWorkResolver) History() ([]*history.RevisionResolver, error) {
+	return history.Resolvers(r.store, r.cid)
+}
@@ -23,12 +23,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strconv"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ipfs/go-cid"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/claimindex"
 )
 
 // Indexer is a META indexer which indexes a stream of META objects
@@ -41,9 +41,10 @@ type Indexer struct {
 }
 
 type jobIn struct {
-	cwrID   *cid.Cid
-	tx      map[string]interface{}
-	indexFn func(cwrID *cid.Cid, tx map[string]interface{}) error
+	resolver *meta.Resolver
+	cwrID    *cid.Cid
+	tx       map[string]interface{}
+	indexFn  func(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error
 }
 
 // NewIndexer returns an Indexer which updates the indexes in the given SQLite3
@@ -61,7 +62,8 @@ func NewIndexer(indexDB *sql.DB, store *meta.Store) (*Indexer, error) {
 }
 
 // Index indexes a stream of META object links which are expected to
-// point at CWRs.
+// point at CWRs, or at claims about CWRs in which case the signer of the
+// claim is also indexed.
 func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 	for {
 		select {
@@ -69,19 +71,7 @@ func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 			if !ok {
 				return nil
 			}
-			obj, err := i.store.Get(cid)
-			if err != nil {
-				return err
-			}
-			i.sqlTx, err = i.indexDB.Begin()
-			if err != nil {
-				return err
-			}
-			if err := i.index(obj); err != nil {
-				i.sqlTx.Rollback()
-				return err
-			}
-			if err := i.sqlTx.Commit(); err != nil {
+			if err := i.indexCid(ctx, cid); err != nil {
 				return err
 			}
 		case <-ctx.Done():
@@ -90,9 +80,40 @@ func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 	}
 }
 
-// index indexes a CWR based on its NWR,REV
-func (i *Indexer) index(cwr *meta.Object) (err error) {
-	graph := meta.NewGraph(i.store, cwr)
+// indexCid indexes the CWR or claim with the given CID in a single SQL
+// transaction, using a resolver which is cancelled once the CWR has been
+// indexed so that it does not keep fetching records in the background.
+func (i *Indexer) indexCid(ctx context.Context, id *cid.Cid) error {
+	obj, err := i.store.Get(id)
+	if err != nil {
+		return err
+	}
+	obj, claim, err := meta.ResolveClaim(i.store, obj)
+	if err != nil {
+		return err
+	}
+	i.sqlTx, err = i.indexDB.Begin()
+	if err != nil {
+		return err
+	}
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := i.index(meta.NewResolver(rctx, i.store, 0), obj); err != nil {
+		i.sqlTx.Rollback()
+		return err
+	}
+	if claim != nil {
+		if err := claimindex.Index(i.sqlTx, claim); err != nil {
+			i.sqlTx.Rollback()
+			return err
+		}
+	}
+	return i.sqlTx.Commit()
+}
+
+// index indexes a CWR based on its NWR,REV, loading records using the given
+// resolver.
+func (i *Indexer) index(resolver *meta.Resolver, cwr *meta.Object) (err error) {
 	jobs := make(chan jobIn)
 	results := make(chan error)
 
@@ -100,7 +121,7 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 		"HDR": i.indexTransmissionHeader,
 		"TRL": i.indexTransmissionTrailer,
 	} {
-		v, err := graph.Get("Records", field)
+		v, err := resolver.Resolve(cwr, "Records", field)
 		if meta.IsPathNotFound(err) {
 			continue
 		} else if err != nil {
@@ -110,16 +131,21 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 		if !ok {
 			return fmt.Errorf("unexpected field type for %q, expected *cid.Cid, got %T", field, v)
 		}
-		if err := i.indexRecord(cwr.Cid(), id, indexFn); err != nil {
+		if err := i.indexRecord(resolver, cwr.Cid(), id, indexFn); err != nil {
 			return err
 		}
 	}
-	v, err := graph.Get("Groups")
+
+	// resolve the groups once rather than resolving each transaction
+	// from the root, which would decode the whole CWR each time
+	v, err := resolver.Resolve(cwr, "Groups")
 	if err != nil {
 		return err
 	}
-
-	numberOfGroups := len(v.([]interface{}))
+	groups, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected field type for \"Groups\", expected []interface{}, got %T", v)
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(concurrentWorkNum)
@@ -133,7 +159,7 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 		defer func() {
 			results <- err
 		}()
-		for field, indexFn := range map[string]func(cwrID *cid.Cid, tx map[string]interface{}) error{
+		for field, indexFn := range map[string]func(*meta.Resolver, *cid.Cid, map[string]interface{}) error{
 			"NWR": i.indexNWR,
 			"REV": i.indexNWR,
 			"ISW": i.indexISW,
@@ -142,9 +168,9 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 			"ACK": i.indexACK,
 		} {
 
-			for k := 0; k < numberOfGroups; k++ {
+			for _, group := range groups {
 
-				v, err := graph.Get("Groups", strconv.Itoa(k), "GRH")
+				v, err := resolver.ResolveValue(group, "GRH")
 				if meta.IsPathNotFound(err) {
 					continue
 				} else if err != nil {
@@ -155,29 +181,31 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 				if !ok {
 					return fmt.Errorf("unexpected field type for %q, expected *cid.Cid, got %T", field, v)
 				}
-				if err := i.indexRecord(cwr.Cid(), id, i.indexGroupHeader); err != nil {
+				if err := i.indexRecord(resolver, cwr.Cid(), id, i.indexGroupHeader); err != nil {
 					return err
 				}
-				v, err = graph.Get("Groups", strconv.Itoa(k), "Transactions", field)
+				v, err = resolver.ResolveValue(group, "Transactions", field)
 				if meta.IsPathNotFound(err) {
 					continue
 				} else if err != nil {
 					return err
 				}
-				numberOfTx := len(v.([]interface{}))
-
-				for j := 0; j < numberOfTx; j++ {
-					v, err := graph.Get("Groups", strconv.Itoa(k), "Transactions", field, strconv.Itoa(j))
-					if meta.IsPathNotFound(err) {
-						continue
-					} else if err != nil {
-						return err
-					}
+
+				txs, ok := v.([]interface{})
+				if !ok {
+					return fmt.Errorf("unexpected field type for %q, expected []interface{}, got %T", field, v)
+				}
+
+				// prefetch the records of every transaction so
+				// that workers do not wait on the store
+				resolver.PrefetchLinks(txs)
+
+				for _, v := range txs {
 					tx, ok := v.(map[string]interface{})
 					if !ok {
 						return fmt.Errorf("unexpected field type .Expected map[string]interface{}, got %T", v)
 					}
-					jobs <- jobIn{cwr.Cid(), tx, indexFn}
+					jobs <- jobIn{resolver, cwr.Cid(), tx, indexFn}
 				}
 			}
 		}
@@ -201,14 +229,14 @@ func (i *Indexer) index(cwr *meta.Object) (err error) {
 
 func (i *Indexer) worker(jobs <-chan jobIn, results chan<- error) {
 	for job := range jobs {
-		results <- job.indexFn(job.cwrID, job.tx)
+		results <- job.indexFn(job.resolver, job.cwrID, job.tx)
 	}
 }
 
 // indexRecord indexes a particular CWR record using the provided index
 // function.
-func (i *Indexer) indexRecord(cwrID, cid *cid.Cid, indexFn func(*cid.Cid, *meta.Object) error) error {
-	obj, err := i.store.Get(cid)
+func (i *Indexer) indexRecord(resolver *meta.Resolver, cwrID, cid *cid.Cid, indexFn func(*cid.Cid, *meta.Object) error) error {
+	obj, err := resolver.Get(cid)
 	if err != nil {
 		return err
 	}
@@ -276,7 +304,7 @@ func (i *Indexer) indexPublisherControlledBySubmiter(cwrID *cid.Cid, txCid *cid.
 
 // indexNWR indexes the given cwr transaction by indexing each transacation's record and link it to its
 // transaction.
-func (i *Indexer) indexNWR(cwrID *cid.Cid, tx map[string]interface{}) error {
+func (i *Indexer) indexNWR(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error {
 	mainRecordTx, ok := tx["MainRecord"].(map[string]interface{})
 	if !ok {
 		return fmt.Errorf("error indexing CWR: expected MainRecord property to be map[string]interface{}, got %T", tx["MainRecord"])
@@ -288,7 +316,7 @@ func (i *Indexer) indexNWR(cwrID *cid.Cid, tx map[string]interface{}) error {
 			return fmt.Errorf("error indexing CWR: expected REV property to be *cid.Cid, got %T", mainRecordTx["REV"])
 		}
 	}
-	obj, err := i.store.Get(nwrCid)
+	obj, err := resolver.Get(nwrCid)
 	if err != nil {
 		return err
 	}
@@ -298,7 +326,7 @@ func (i *Indexer) indexNWR(cwrID *cid.Cid, tx map[string]interface{}) error {
 	}
 
 	for _, spuCid := range tx["DetailRecords"].(map[string]interface{})["SPU"].([]interface{}) {
-		obj, err := i.store.Get(spuCid.(*cid.Cid))
+		obj, err := resolver.Get(spuCid.(*cid.Cid))
 		if err != nil {
 			return err
 		}
@@ -309,22 +337,22 @@ func (i *Indexer) indexNWR(cwrID *cid.Cid, tx map[string]interface{}) error {
 	return nil
 }
 
-func (i *Indexer) indexISW(cwrID *cid.Cid, tx map[string]interface{}) error {
+func (i *Indexer) indexISW(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error {
 	// TODO: index ISW
 	return nil
 }
 
-func (i *Indexer) indexACK(cwrID *cid.Cid, tx map[string]interface{}) error {
+func (i *Indexer) indexACK(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error {
 	// TODO: index ACK
 	return nil
 }
 
-func (i *Indexer) indexEXC(cwrID *cid.Cid, tx map[string]interface{}) error {
+func (i *Indexer) indexEXC(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error {
 	// TODO: index EXC
 	return nil
 }
 
-func (i *Indexer) indexAGR(cwrID *cid.Cid, tx map[string]interface{}) error {
+func (i *Indexer) indexAGR(resolver *meta.Resolver, cwrID *cid.Cid, tx map[string]interface{}) error {
 	// TODO: index AGR
 	return nil
 }
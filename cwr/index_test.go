@@ -20,12 +20,14 @@
 package cwr
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -177,6 +179,77 @@ func TestIndex(t *testing.T) {
 	}
 }
 
+// BenchmarkIndex benchmarks indexing a CWR with many groups of transactions,
+// which are stored inline in the root CWR object.
+func BenchmarkIndex(b *testing.B) {
+	tmpDir, err := ioutil.TempDir("", "cwr-index-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	store, err := meta.NewFSStore(tmpDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cwrID, err := NewConverter(store).ConvertCWR(strings.NewReader(testMultiGroupCWR(20, 50)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		db, err := sql.Open("sqlite3", filepath.Join(tmpDir, fmt.Sprintf("index-%d.db", n)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		indexer, err := NewIndexer(db, store)
+		if err != nil {
+			b.Fatal(err)
+		}
+		stream := make(chan *cid.Cid, 1)
+		stream <- cwrID
+		close(stream)
+		b.StartTimer()
+
+		if err := indexer.Index(context.Background(), stream); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		db.Close()
+		b.StartTimer()
+	}
+}
+
+// testMultiGroupCWR returns a CWR file with the given number of groups each
+// containing the given number of NWR transactions, based on the records in
+// testdata/example_nwr.cwr.
+func testMultiGroupCWR(groups, transactions int) string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "example_nwr.cwr"))
+	if err != nil {
+		panic(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	hdr, grh, nwr, spu, grt, trl := lines[0], lines[1], lines[2], lines[3], lines[6], lines[7]
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, hdr)
+	for g := 0; g < groups; g++ {
+		fmt.Fprintln(&buf, grh)
+		for t := 0; t < transactions; t++ {
+			// give each work a distinct title so that each
+			// transaction is a distinct object
+			title := fmt.Sprintf("WORK %05d %05d", g, t)
+			fmt.Fprintln(&buf, nwr[:19]+title+nwr[19+len(title):])
+			fmt.Fprintln(&buf, spu)
+		}
+		fmt.Fprintln(&buf, grt)
+	}
+	fmt.Fprintln(&buf, trl)
+	return buf.String()
+}
+
 func newTestIndex() (x *testIndex, err error) {
 	// convert the test cwr to META object
 	x = &testIndex{}
@@ -19,7 +19,10 @@
 
 package cwr
 
-import "github.com/meta-network/go-meta/migrate"
+import (
+	"github.com/meta-network/go-meta/claimindex"
+	"github.com/meta-network/go-meta/migrate"
+)
 
 // migrations is a set of database migrations to run on a SQLite3 database
 // to prepare it for indexing a META stream of CWRs.
@@ -91,4 +94,8 @@ CREATE INDEX transmission_header_cwr_id_idx      ON transmission_header (cwr_id)
 CREATE INDEX transmission_sender_name_id_idx      ON transmission_header (sender_name);
 `,
 	)
+
+	// migration 2 creates an index of the signers of claims about
+	// indexed CWRs so that they can be filtered by trusted sources
+	migrations.Add(2, claimindex.Migration)
 }
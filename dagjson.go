@@ -0,0 +1,212 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/lmars/cbor/go"
+	"github.com/lmars/go-ipld-cbor"
+)
+
+// DecodeJSON decodes an object from its IPLD DAG-JSON representation, which
+// is the representation produced by Object.MarshalJSON, where links are
+// represented as {"/": "<cid>"} and bytes as {"/": {"bytes": "<base64>"}}.
+//
+// Numbers containing a decimal point or exponent decode as floats and all
+// other numbers as integers, which is how Object.MarshalJSON distinguishes
+// them (e.g. the float 2 is marshalled as 2.0).
+//
+// The object is re-encoded as canonical CBOR, so an object which has been
+// marshalled to JSON decodes back to an object with the same CID.
+func DecodeJSON(r io.Reader) (*Object, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("meta: error decoding JSON: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("meta: unexpected data after JSON object")
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("meta: expected JSON object, got %T", v)
+	}
+	v, err := fromJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(v)
+}
+
+// fromJSON converts a value decoded from DAG-JSON into a value which can be
+// encoded as CBOR, converting links to CIDs and numbers to either integers
+// or floats.
+func fromJSON(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if x, ok := v["/"]; ok && len(v) == 1 {
+			return fromJSONLink(x)
+		}
+		for key, x := range v {
+			y, err := fromJSON(x)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = y
+		}
+		return v, nil
+	case []interface{}:
+		for i, x := range v {
+			y, err := fromJSON(x)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = y
+		}
+		return v, nil
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+			if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+				return u, nil
+			}
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid JSON number %q", v)
+		}
+		return f, nil
+	default:
+		return v, nil
+	}
+}
+
+// toJSON converts a value decoded from CBOR into a value which marshals as
+// DAG-JSON, representing bytes as {"/": {"bytes": "<base64>"}} and floats
+// with either a decimal point or an exponent (links already marshal as
+// {"/": "<cid>"}).
+func toJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, x := range v {
+			m[key] = toJSON(x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = toJSON(x)
+		}
+		return l
+	case []byte:
+		return map[string]interface{}{
+			"/": map[string]string{
+				"bytes": base64.RawStdEncoding.EncodeToString(v),
+			},
+		}
+	case float32:
+		return jsonFloat(v)
+	case float64:
+		return jsonFloat(v)
+	default:
+		return v
+	}
+}
+
+// jsonFloat is a float which always marshals with either a decimal point or
+// an exponent so that it decodes back to a float rather than an integer.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	x := float64(f)
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return nil, fmt.Errorf("meta: unsupported JSON float %v", x)
+	}
+	s := strconv.FormatFloat(x, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return []byte(s), nil
+}
+
+// fromJSONLink converts the value of a DAG-JSON {"/": ...} object into
+// either a CID or bytes.
+func fromJSONLink(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		id, err := cid.Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid link %q: %s", v, err)
+		}
+		return id, nil
+	case map[string]interface{}:
+		s, ok := v["bytes"].(string)
+		if !ok || len(v) != 1 {
+			break
+		}
+		b, err := base64.RawStdEncoding.DecodeString(s)
+		if err != nil {
+			b, err = base64.StdEncoding.DecodeString(s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid bytes %q: %s", s, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("meta: invalid link value of type %T", v)
+}
+
+// DecodeCBOR decodes an object from CBOR encoded data, re-encoding it as
+// canonical CBOR if it is not already.
+func DecodeCBOR(r io.Reader) (obj *Object, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("meta: error decoding CBOR: %v", r)
+		}
+	}()
+	dec := cbor.NewDecoder(bytes.NewReader(data))
+	dec.TagDecoders[cbornode.CBORTagLink] = &cbornode.IpldLinkDecoder{}
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("meta: error decoding CBOR: %s", err)
+	}
+	if _, ok := v.(map[interface{}]interface{}); !ok {
+		return nil, fmt.Errorf("meta: expected CBOR map, got %T", v)
+	}
+	return Encode(v)
+}
@@ -0,0 +1,119 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	link := MustEncode(map[string]interface{}{"name": "linked"})
+	objects := []*Object{
+		link,
+		MustEncode(map[string]interface{}{
+			"@type":  "Example",
+			"int":    42,
+			"neg":    -7,
+			"float":  1.5,
+			"whole":  2.0,
+			"big":    1e21,
+			"huge":   uint64(math.MaxUint64),
+			"bytes":  []byte("hello"),
+			"bool":   true,
+			"null":   nil,
+			"string": "foo",
+			"link":   link.Cid(),
+			"links":  []*cid.Cid{link.Cid(), link.Cid()},
+			"nested": map[string]interface{}{
+				"@value": "bar",
+				"list":   []interface{}{1, "two", map[string]interface{}{"three": link.Cid()}, []byte{0, 1}, 3.0},
+			},
+		}),
+	}
+
+	// check objects decode back to the same CID from both JSON and CBOR
+	for _, obj := range objects {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeJSON(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Cid().Equals(obj.Cid()) {
+			t.Fatalf("expected JSON %s to decode to %s, got %s", data, obj.Cid(), decoded.Cid())
+		}
+		decoded, err = DecodeCBOR(bytes.NewReader(obj.RawData()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Cid().Equals(obj.Cid()) {
+			t.Fatalf("expected CBOR to decode to %s, got %s", obj.Cid(), decoded.Cid())
+		}
+	}
+
+	// check key order does not affect the CID and links are decoded
+	obj, err := DecodeJSON(strings.NewReader(`{"b": 1, "a": {"/": "` + link.Cid().String() + `"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MustEncode(map[string]interface{}{"a": link.Cid(), "b": 1})
+	if !obj.Cid().Equals(expected.Cid()) {
+		t.Fatalf("expected CID %s, got %s", expected.Cid(), obj.Cid())
+	}
+	if l, err := obj.GetLink("a"); err != nil {
+		t.Fatal(err)
+	} else if !l.Cid.Equals(link.Cid()) {
+		t.Fatalf("expected link to %s, got %s", link.Cid(), l.Cid)
+	}
+
+	// check bytes are decoded
+	obj, err = DecodeJSON(strings.NewReader(`{"data": {"/": {"bytes": "aGVsbG8"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = MustEncode(map[string]interface{}{"data": []byte("hello")})
+	if !obj.Cid().Equals(expected.Cid()) {
+		t.Fatalf("expected CID %s, got %s", expected.Cid(), obj.Cid())
+	}
+
+	// check invalid input is rejected
+	for _, s := range []string{
+		`[1, 2]`,
+		`{"a": 1} {"b": 2}`,
+		`{"a": {"/": "not-a-cid"}}`,
+		`{"a": {"/": 1}}`,
+		`{"a": `,
+	} {
+		if _, err := DecodeJSON(strings.NewReader(s)); err == nil {
+			t.Fatalf("expected error decoding %s", s)
+		}
+	}
+	if _, err := DecodeCBOR(strings.NewReader("\x01")); err == nil {
+		t.Fatal("expected error decoding non-map CBOR")
+	}
+}
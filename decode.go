@@ -0,0 +1,377 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+// valueKey is the property which contains the value of a value object (see
+// https://www.w3.org/TR/json-ld/#value-objects).
+const valueKey = "@value"
+
+// DecodePath decodes the value at the given path into the value pointed to by
+// v, following links both to reach the path and whilst decoding.
+//
+// Structs are decoded from objects (or maps) using the path in each field's
+// "meta" struct tag, which is a slash separated path relative to the object
+// which can itself traverse links, defaulting to the field name. Fields whose
+// path does not exist are left unset. For example:
+//
+//   var header struct {
+//     MessageID  string    `meta:"MessageId/@value"`
+//     SenderName string    `meta:"MessageSender/PartyName/FullName/@value"`
+//     Created    time.Time `meta:"MessageCreatedDateTime"`
+//     Recipient  *cid.Cid  `meta:"MessageRecipient"`
+//   }
+//   err := graph.DecodePath(&header, "NewReleaseMessage", "MessageHeader")
+//
+// Strings, numbers, booleans and times are decoded from either plain values
+// or @value objects, *cid.Cid values are decoded from links without following
+// them, and slices are decoded from either lists or single values.
+func (g *Graph) DecodePath(v interface{}, path ...string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("meta: cannot decode into non-pointer %T", v)
+	}
+	var src interface{} = g.root.Cid()
+	if len(path) > 0 {
+		var err error
+		src, err = g.Get(path...)
+		if err != nil {
+			return err
+		}
+		if obj, ok := src.(*Object); ok {
+			src = obj.Cid()
+		}
+	}
+	d := &decoder{get: g.get}
+	if err := d.decode(src, rv.Elem()); err != nil {
+		return fmt.Errorf("meta: error decoding %s into %T: %s", strings.Join(path, "/"), v, err)
+	}
+	return nil
+}
+
+var (
+	cidType  = reflect.TypeOf((*cid.Cid)(nil))
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// decoder decodes generic values into Go values, loading linked objects
+// using get.
+type decoder struct {
+	get func(*cid.Cid) (*Object, error)
+}
+
+// decode decodes src into dst.
+func (d *decoder) decode(src interface{}, dst reflect.Value) error {
+	// decode links into *cid.Cid values rather than following them
+	if dst.Type() == cidType {
+		id, ok := src.(*cid.Cid)
+		if !ok {
+			return fmt.Errorf("expected link, got %T", src)
+		}
+		dst.Set(reflect.ValueOf(id))
+		return nil
+	}
+
+	// follow links for everything else
+	src, err := d.load(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dst.Kind() == reflect.Interface && dst.NumMethod() == 0:
+		if src != nil {
+			dst.Set(reflect.ValueOf(src))
+		}
+		return nil
+	case dst.Kind() == reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return d.decode(src, dst.Elem())
+	case dst.Type() == timeType:
+		t, err := toTime(unwrapValue(src))
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	case dst.Kind() == reflect.Struct:
+		return d.decodeStruct(src, dst)
+	case dst.Kind() == reflect.Slice:
+		list, ok := src.([]interface{})
+		if !ok {
+			// decode a single value into a one element slice
+			list = []interface{}{src}
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(list), len(list))
+		for i, x := range list {
+			if err := d.decode(x, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %s", i, err)
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case dst.Kind() == reflect.Map && dst.Type().Key().Kind() == reflect.String:
+		m, ok := src.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected map, got %T", src)
+		}
+		out := reflect.MakeMap(dst.Type())
+		for key, x := range m {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := d.decode(x, elem); err != nil {
+				return fmt.Errorf("key %q: %s", key, err)
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(out)
+		return nil
+	}
+
+	src = unwrapValue(src)
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", src)
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		b, err := toBool(src)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(src)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowInt(i) {
+			return fmt.Errorf("value %d overflows %s", i, dst.Type())
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := toUint(src)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowUint(u) {
+			return fmt.Errorf("value %d overflows %s", u, dst.Type())
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(src)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot decode into value of type %s", dst.Type())
+	}
+	return nil
+}
+
+// decodeStruct decodes src into the fields of the struct dst.
+func (d *decoder) decodeStruct(src interface{}, dst reflect.Value) error {
+	if _, ok := src.(map[string]interface{}); !ok {
+		return fmt.Errorf("expected object, got %T", src)
+	}
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("meta")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		v, ok, err := d.resolve(src, strings.Split(tag, "/"))
+		if err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		} else if !ok {
+			continue
+		}
+		if err := d.decode(v, dst.Field(i)); err != nil {
+			return fmt.Errorf("field %s: %s", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolve returns the value at the given path from v, following any links
+// and returning false if the path does not exist.
+func (d *decoder) resolve(v interface{}, path []string) (interface{}, bool, error) {
+	for _, key := range path {
+		x, err := d.load(v)
+		if err != nil {
+			return nil, false, err
+		}
+		switch x := x.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = x[key]; !ok {
+				return nil, false, nil
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false, nil
+			}
+			v = x[i]
+		default:
+			return nil, false, nil
+		}
+	}
+	return v, true, nil
+}
+
+// load returns the properties of the linked object if v is a link, and
+// otherwise returns v.
+func (d *decoder) load(v interface{}) (interface{}, error) {
+	id, ok := v.(*cid.Cid)
+	if !ok {
+		return v, nil
+	}
+	obj, err := d.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Properties()
+}
+
+// unwrapValue returns the @value property of v if it is a value object, and
+// otherwise returns v.
+func unwrapValue(v interface{}) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		if x, ok := m[valueKey]; ok {
+			return x
+		}
+	}
+	return v
+}
+
+// toInt converts a number or numeric string to an int64.
+func toInt(v interface{}) (int64, error) {
+	if s, ok := v.(string); ok {
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("integer %d overflows int64", rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == float64(int64(f)) {
+			return int64(f), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot convert %T to an integer", v)
+}
+
+// toUint converts a non-negative number or numeric string to a uint64.
+func toUint(v interface{}) (uint64, error) {
+	if s, ok := v.(string); ok {
+		return strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	}
+	i, err := toInt(v)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, fmt.Errorf("value %d overflows uint64", i)
+	}
+	return uint64(i), nil
+}
+
+// toFloat converts a number or numeric string to a float64.
+func toFloat(v interface{}) (float64, error) {
+	if s, ok := v.(string); ok {
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to a number", v)
+}
+
+// toBool converts a boolean or boolean string to a bool.
+func toBool(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	return false, fmt.Errorf("cannot convert %T to a boolean", v)
+}
+
+// timeLayouts are the layouts used to parse times, in order.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// toTime parses a string as an RFC 3339 date and time, a date and time
+// without a timezone (which is assumed to be UTC) or a date.
+func toTime(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to a time", v)
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
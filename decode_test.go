@@ -0,0 +1,222 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestObjectTypedAccessors(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	link := MustEncode(map[string]interface{}{"name": "linked"})
+	if err := store.Put(link); err != nil {
+		t.Fatal(err)
+	}
+	obj := MustEncode(map[string]interface{}{
+		"int":       42,
+		"intString": map[string]interface{}{"@value": "-7"},
+		"bool":      map[string]interface{}{"@value": true},
+		"boolStr":   "false",
+		"time":      map[string]interface{}{"@value": "2017-06-01T12:30:00Z"},
+		"date":      "2017-06-01",
+		"link":      link.Cid(),
+		"links":     []*cid.Cid{link.Cid(), link.Cid()},
+		"string":    "foo",
+	})
+
+	if v, err := obj.GetInt("int"); err != nil {
+		t.Fatal(err)
+	} else if v != 42 {
+		t.Fatalf("expected int 42, got %d", v)
+	}
+	if v, err := obj.GetInt("intString"); err != nil {
+		t.Fatal(err)
+	} else if v != -7 {
+		t.Fatalf("expected intString -7, got %d", v)
+	}
+	if _, err := obj.GetInt("string"); err == nil {
+		t.Fatal("expected error getting string as int")
+	}
+	if v, err := obj.GetBool("bool"); err != nil {
+		t.Fatal(err)
+	} else if !v {
+		t.Fatal("expected bool to be true")
+	}
+	if v, err := obj.GetBool("boolStr"); err != nil {
+		t.Fatal(err)
+	} else if v {
+		t.Fatal("expected boolStr to be false")
+	}
+	expected := time.Date(2017, 6, 1, 12, 30, 0, 0, time.UTC)
+	if v, err := obj.GetTime("time"); err != nil {
+		t.Fatal(err)
+	} else if !v.Equal(expected) {
+		t.Fatalf("expected time %s, got %s", expected, v)
+	}
+	expected = time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC)
+	if v, err := obj.GetTime("date"); err != nil {
+		t.Fatal(err)
+	} else if !v.Equal(expected) {
+		t.Fatalf("expected date %s, got %s", expected, v)
+	}
+	if v, err := obj.GetValue("intString"); err != nil {
+		t.Fatal(err)
+	} else if v != "-7" {
+		t.Fatalf("expected value %q, got %v", "-7", v)
+	}
+	for _, key := range []string{"link", "links"} {
+		cids, err := obj.GetLinks(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cids) == 0 {
+			t.Fatalf("expected %s to have links", key)
+		}
+		for _, id := range cids {
+			if !id.Equals(link.Cid()) {
+				t.Fatalf("expected %s to link to %s, got %s", key, link.Cid(), id)
+			}
+		}
+	}
+	if _, err := obj.GetLinks("string"); err == nil {
+		t.Fatal("expected error getting string as links")
+	}
+	if _, err := obj.GetInt("missing"); err == nil {
+		t.Fatal("expected error getting missing key")
+	}
+}
+
+func TestGraphDecodePath(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *Object {
+		obj := MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	name := put(map[string]interface{}{"@value": "Alice"})
+	alice := put(map[string]interface{}{
+		"name": name.Cid(),
+		"born": map[string]interface{}{"@value": "1970-01-02"},
+	})
+	bob := put(map[string]interface{}{
+		"name": map[string]interface{}{"@value": "Bob"},
+		"age":  "42",
+	})
+	root := put(map[string]interface{}{
+		"title":   map[string]interface{}{"@value": "Example"},
+		"author":  alice.Cid(),
+		"members": []*cid.Cid{alice.Cid(), bob.Cid()},
+		"tags":    "single",
+		"counts":  map[string]interface{}{"a": 1, "b": 2},
+	})
+	graph := NewGraph(store, root)
+
+	type person struct {
+		Name string     `meta:"name/@value"`
+		Born *time.Time `meta:"born"`
+		Age  int        `meta:"age"`
+	}
+	var doc struct {
+		Title      string           `meta:"title"`
+		Author     *cid.Cid         `meta:"author"`
+		AuthorName string           `meta:"author/name/@value"`
+		FirstName  string           `meta:"members/0/name"`
+		Members    []person         `meta:"members"`
+		Tags       []string         `meta:"tags"`
+		Counts     map[string]int64 `meta:"counts"`
+		Missing    string           `meta:"missing/@value"`
+		Ignored    string           `meta:"-"`
+	}
+	if err := graph.DecodePath(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Example" {
+		t.Fatalf("expected title %q, got %q", "Example", doc.Title)
+	}
+	if doc.Author == nil || !doc.Author.Equals(alice.Cid()) {
+		t.Fatalf("expected author %s, got %v", alice.Cid(), doc.Author)
+	}
+	if doc.AuthorName != "Alice" {
+		t.Fatalf("expected author name %q, got %q", "Alice", doc.AuthorName)
+	}
+	if doc.FirstName != "Alice" {
+		t.Fatalf("expected first name %q, got %q", "Alice", doc.FirstName)
+	}
+	if len(doc.Members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(doc.Members))
+	}
+	if doc.Members[0].Name != "Alice" || doc.Members[0].Born == nil || doc.Members[0].Born.Day() != 2 {
+		t.Fatalf("unexpected first member: %+v", doc.Members[0])
+	}
+	if doc.Members[1].Name != "Bob" || doc.Members[1].Born != nil || doc.Members[1].Age != 42 {
+		t.Fatalf("unexpected second member: %+v", doc.Members[1])
+	}
+	if len(doc.Tags) != 1 || doc.Tags[0] != "single" {
+		t.Fatalf("expected tags [single], got %v", doc.Tags)
+	}
+	if doc.Counts["a"] != 1 || doc.Counts["b"] != 2 {
+		t.Fatalf("unexpected counts: %v", doc.Counts)
+	}
+
+	// decode a value at a path
+	var member person
+	if err := graph.DecodePath(&member, "members", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if member.Name != "Bob" {
+		t.Fatalf("expected member name %q, got %q", "Bob", member.Name)
+	}
+
+	// check type mismatches are errors
+	var invalid struct {
+		Title int `meta:"title"`
+	}
+	if err := graph.DecodePath(&invalid); err == nil {
+		t.Fatal("expected error decoding string into int")
+	}
+
+	// check integers which overflow int64 are errors rather than wrapping
+	big := NewGraph(store, put(map[string]interface{}{"n": uint64(math.MaxUint64)}))
+	var overflow struct {
+		N int64 `meta:"n"`
+	}
+	if err := big.DecodePath(&overflow); err == nil {
+		t.Fatalf("expected error decoding overflowing integer, got %d", overflow.N)
+	}
+	var unsigned struct {
+		N uint64 `meta:"n"`
+	}
+	if err := big.DecodePath(&unsigned); err != nil {
+		t.Fatal(err)
+	}
+	if unsigned.N != math.MaxUint64 {
+		t.Fatalf("expected %d, got %d", uint64(math.MaxUint64), unsigned.N)
+	}
+	if err := graph.DecodePath(member); err == nil {
+		t.Fatal("expected error decoding into non-pointer")
+	}
+}
@@ -0,0 +1,180 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// ChangeType is the type of a change between two graphs.
+type ChangeType string
+
+const (
+	// Added means the path only exists in the new graph.
+	Added ChangeType = "added"
+
+	// Removed means the path only exists in the old graph.
+	Removed ChangeType = "removed"
+
+	// Changed means the path exists in both graphs but has a different
+	// value.
+	Changed ChangeType = "changed"
+)
+
+// Change is a difference between two graphs at a particular path.
+//
+// Old and New are the values in the old and new graph respectively, with
+// links represented as *cid.Cid values.
+type Change struct {
+	Type ChangeType  `json:"type"`
+	Path []string    `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Diff returns the changes between the graph rooted at the object with CID a
+// and the graph rooted at the object with CID b, in path order.
+//
+// Links are followed so that changes are reported at the deepest path
+// possible, but sub-graphs with identical CIDs are skipped. A link to an
+// object which is not in the store is compared by CID only, but
+// datastore.ErrNotFound is returned if either root is not in the store.
+func Diff(store *Store, a, b *cid.Cid) ([]*Change, error) {
+	for _, id := range []*cid.Cid{a, b} {
+		if has, err := store.Has(id); err != nil {
+			return nil, err
+		} else if !has {
+			return nil, datastore.ErrNotFound
+		}
+	}
+	d := &differ{store: store}
+	if err := d.diff(nil, a, b); err != nil {
+		return nil, err
+	}
+	return d.changes, nil
+}
+
+// differ accumulates the changes between two graphs.
+type differ struct {
+	store   *Store
+	changes []*Change
+}
+
+func (d *differ) add(typ ChangeType, path []string, old, new interface{}) {
+	d.changes = append(d.changes, &Change{Type: typ, Path: path, Old: old, New: new})
+}
+
+func (d *differ) diff(path []string, old, new interface{}) error {
+	switch old := old.(type) {
+	case *cid.Cid:
+		new, ok := new.(*cid.Cid)
+		if !ok {
+			break
+		}
+		if old.Equals(new) {
+			return nil
+		}
+		oldProps, err := d.properties(old)
+		if err != nil {
+			return err
+		}
+		newProps, err := d.properties(new)
+		if err != nil {
+			return err
+		}
+		if oldProps == nil || newProps == nil {
+			break
+		}
+		return d.diff(path, oldProps, newProps)
+
+	case map[string]interface{}:
+		new, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(old)+len(new))
+		for key := range old {
+			keys = append(keys, key)
+		}
+		for key := range new {
+			if _, ok := old[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			oldV, inOld := old[key]
+			newV, inNew := new[key]
+			switch {
+			case !inNew:
+				d.add(Removed, appendPath(path, key), oldV, nil)
+			case !inOld:
+				d.add(Added, appendPath(path, key), nil, newV)
+			default:
+				if err := d.diff(appendPath(path, key), oldV, newV); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case []interface{}:
+		new, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(old) || i < len(new); i++ {
+			p := appendPath(path, strconv.Itoa(i))
+			switch {
+			case i >= len(new):
+				d.add(Removed, p, old[i], nil)
+			case i >= len(old):
+				d.add(Added, p, nil, new[i])
+			default:
+				if err := d.diff(p, old[i], new[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		d.add(Changed, path, old, new)
+	}
+	return nil
+}
+
+// properties loads the properties of the object with the given CID, returning
+// nil if it is not in the store.
+func (d *differ) properties(id *cid.Cid) (map[string]interface{}, error) {
+	obj, err := d.store.Get(id)
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return obj.Properties()
+}
@@ -0,0 +1,86 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestDiff(t *testing.T) {
+	graph := testGraph(t)
+
+	// make some changes to the graph
+	newGraph, err := graph.Set([]string{"children", "1", "children", "0", "name"}, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newGraph, err = newGraph.Set([]string{"age"}, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newGraph, err = newGraph.Delete("children", "1", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(graph.store, graph.Root().Cid(), newGraph.Root().Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type change struct {
+		typ  ChangeType
+		path string
+		old  interface{}
+		new  interface{}
+	}
+	expected := []change{
+		{Added, "age", nil, uint64(42)},
+		{Changed, "children/1/children/0/name", "grandchild", "renamed"},
+		{Removed, "children/1/name", "child1", nil},
+	}
+	actual := make([]change, len(changes))
+	for i, c := range changes {
+		actual[i] = change{c.Type, strings.Join(c.Path, "/"), c.Old, c.New}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected changes:\nexpected: %v\nactual:   %v", expected, actual)
+	}
+
+	// check identical graphs have no changes
+	changes, err = Diff(graph.store, graph.Root().Cid(), graph.Root().Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+	// check a missing root is reported as not found
+	missing := MustEncode(map[string]string{"name": "missing"})
+	if _, err := Diff(graph.store, graph.Root().Cid(), missing.Cid()); err != datastore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound diffing a missing root, got %v", err)
+	}
+	if _, err := Diff(graph.store, missing.Cid(), graph.Root().Cid()); err != datastore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound diffing a missing root, got %v", err)
+	}
+}
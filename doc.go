@@ -84,5 +84,12 @@ An object graph can be traversed using a Graph object:
 
 	v, err := graph.Get("some", "path", "through", "the", "graph")
 
+Every object reachable from the root of a graph can be visited with Walk:
+
+	err := graph.Walk(ctx, func(path []string, obj *Object, depth int) error {
+		fmt.Println(strings.Join(path, "/"), obj.Cid())
+		return nil
+	}, &WalkOptions{Order: DepthFirst})
+
 */
 package meta
@@ -29,8 +29,42 @@ import (
 	"github.com/multiformats/go-multihash"
 )
 
+// Blake2b256 is the multihash code of the 256 bit BLAKE2b hash function.
+const Blake2b256 = multihash.BLAKE2B_MIN + 31
+
+// DefaultPrefix returns the CID prefix used by Encode, a CIDv1 of DAG CBOR
+// data hashed with SHA2-256.
+func DefaultPrefix() cid.Prefix {
+	return NewPrefix(multihash.SHA2_256)
+}
+
+// NewPrefix returns a CIDv1 DAG CBOR prefix which uses the hash function with
+// the given multihash code (e.g. multihash.SHA3_256 or Blake2b256) and its
+// default digest length.
+func NewPrefix(hashType uint64) cid.Prefix {
+	return cid.Prefix{
+		Version:  cidV1,
+		Codec:    cid.DagCBOR,
+		MhType:   hashType,
+		MhLength: -1,
+	}
+}
+
 // Encode returns the META object encoding of v.
 func Encode(v interface{}) (*Object, error) {
+	return EncodeWithPrefix(v, DefaultPrefix())
+}
+
+// EncodeWithPrefix returns the META object encoding of v with a CID generated
+// using the given prefix, which must be a CIDv1 DAG CBOR prefix.
+func EncodeWithPrefix(v interface{}, prefix cid.Prefix) (*Object, error) {
+	if prefix.Version != cidV1 {
+		return nil, ErrInvalidCidVersion{prefix.Version}
+	}
+	if prefix.Codec != cid.DagCBOR {
+		return nil, ErrInvalidCodec{prefix.Codec}
+	}
+
 	var buf bytes.Buffer
 	enc := cbor.NewEncoder(&buf)
 	enc.SetFilter(cbornode.EncoderFilter)
@@ -39,12 +73,7 @@ func Encode(v interface{}) (*Object, error) {
 	}
 	data := buf.Bytes()
 
-	cid, err := cid.Prefix{
-		Version:  1,
-		Codec:    cid.DagCBOR,
-		MhType:   multihash.SHA2_256,
-		MhLength: -1,
-	}.Sum(data)
+	cid, err := prefix.Sum(data)
 	if err != nil {
 		return nil, err
 	}
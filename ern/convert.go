@@ -31,6 +31,10 @@ import (
 // Converter converts DDEX ERN XML files into META objects.
 type Converter struct {
 	store *meta.Store
+
+	// Reversible causes files to be converted with the metaxml
+	// Reversible option so that they can be converted back to XML.
+	Reversible bool
 }
 
 // NewConverter returns a Converter which stores META objects in the given META
@@ -43,15 +47,29 @@ func NewConverter(store *meta.Store) *Converter {
 
 // ConvertERN converts the given source XML file into a META object graph and
 // returns the CID of the graph's root META object.
+//
+// The objects are written to the store in a single batch which is only
+// committed if the whole file is converted successfully.
 func (c *Converter) ConvertERN(src io.Reader) (*cid.Cid, error) {
+	batch, err := c.store.Batch()
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Rollback()
+
 	// use the DDEX ERN/382 and AVS XML schemas as the JSON-LD context
 	context := []*cid.Cid{
 		xmlschema.DDEX_Ern382.Cid,
 		xmlschema.DDEX_Avs.Cid,
 	}
-	obj, err := metaxml.EncodeXML(src, context, c.store.Put)
+	obj, err := metaxml.EncodeXML(src, context, batch.Put, &metaxml.EncodeXMLOptions{
+		Reversible: c.Reversible,
+	})
 	if err != nil {
 		return nil, err
 	}
+	if err := batch.Commit(); err != nil {
+		return nil, err
+	}
 	return obj.Cid(), nil
 }
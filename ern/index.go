@@ -25,9 +25,9 @@ import (
 	"fmt"
 
 	"github.com/ipfs/go-cid"
-	"github.com/ipfs/go-ipld-format"
 	"github.com/mattn/go-sqlite3"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/claimindex"
 )
 
 // Indexer is a META indexer which indexes a stream of META objects
@@ -53,7 +53,8 @@ func NewIndexer(db *sql.DB, store *meta.Store) (*Indexer, error) {
 }
 
 // Index indexes a stream of META object links which are expected to
-// point at DDEX ERNs.
+// point at DDEX ERNs, or at claims about DDEX ERNs in which case the signer
+// of the claim is also indexed.
 func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 	for {
 		select {
@@ -61,11 +62,7 @@ func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 			if !ok {
 				return nil
 			}
-			obj, err := i.store.Get(cid)
-			if err != nil {
-				return err
-			}
-			if err := i.index(obj); err != nil {
+			if err := i.indexCid(ctx, cid); err != nil {
 				return err
 			}
 		case <-ctx.Done():
@@ -75,18 +72,39 @@ func (i *Indexer) Index(ctx context.Context, stream chan *cid.Cid) error {
 	return nil
 }
 
-// index indexes a DDEX ERN based on its MessageHeader, WorkList, ResourceList
-// and ReleaseList.
-func (i *Indexer) index(ern *meta.Object) error {
-	graph := meta.NewGraph(i.store, ern)
+// indexCid indexes the DDEX ERN or claim with the given CID, using a resolver
+// which is cancelled once the ERN has been indexed so that it does not keep
+// fetching objects in the background.
+func (i *Indexer) indexCid(ctx context.Context, id *cid.Cid) error {
+	obj, err := i.store.Get(id)
+	if err != nil {
+		return err
+	}
+	obj, claim, err := meta.ResolveClaim(i.store, obj)
+	if err != nil {
+		return err
+	}
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := i.index(meta.NewResolver(rctx, i.store, 0), obj); err != nil {
+		return err
+	}
+	if claim != nil {
+		return claimindex.Index(i.db, claim)
+	}
+	return nil
+}
 
-	for field, indexFn := range map[string]func(*cid.Cid, *meta.Object) error{
+// index indexes a DDEX ERN based on its MessageHeader, WorkList, ResourceList
+// and ReleaseList, loading linked objects using the given resolver.
+func (i *Indexer) index(resolver *meta.Resolver, ern *meta.Object) error {
+	for field, indexFn := range map[string]func(*meta.Resolver, *cid.Cid, *meta.Object) error{
 		"MessageHeader": i.indexMessageHeader,
 		"WorkList":      i.indexWorkList,
 		"ResourceList":  i.indexResourceList,
 		"ReleaseList":   i.indexReleaseList,
 	} {
-		v, err := graph.Get("NewReleaseMessage", field)
+		v, err := resolver.Resolve(ern, "NewReleaseMessage", field)
 		if meta.IsPathNotFound(err) {
 			continue
 		} else if err != nil {
@@ -96,7 +114,7 @@ func (i *Indexer) index(ern *meta.Object) error {
 		if !ok {
 			return fmt.Errorf("unexpected field type for %q, expected *cid.Cid, got %T", field, v)
 		}
-		if err := i.indexProperty(ern.Cid(), id, indexFn); err != nil {
+		if err := i.indexProperty(resolver, ern.Cid(), id, indexFn); err != nil {
 			return err
 		}
 	}
@@ -106,12 +124,12 @@ func (i *Indexer) index(ern *meta.Object) error {
 
 // indexProperty indexes a particular ERN property using the provided index
 // function.
-func (i *Indexer) indexProperty(ernID, cid *cid.Cid, indexFn func(*cid.Cid, *meta.Object) error) error {
-	obj, err := i.store.Get(cid)
+func (i *Indexer) indexProperty(resolver *meta.Resolver, ernID, cid *cid.Cid, indexFn func(*meta.Resolver, *cid.Cid, *meta.Object) error) error {
+	obj, err := resolver.Get(cid)
 	if err != nil {
 		return err
 	}
-	return indexFn(ernID, obj)
+	return indexFn(resolver, ernID, obj)
 }
 
 // isUniqueErr determines whether an error is a SQLite3 uniqueness error.
@@ -125,137 +143,79 @@ func isUniqueErr(err error) bool {
 
 // indexMessageHeader indexes an ERN MessageHeader based on its MessageId,
 // MessageThreadId, MessageSender, MessageRecipient and MessageCreatedDateTime.
-func (i *Indexer) indexMessageHeader(ernID *cid.Cid, obj *meta.Object) error {
-	graph := meta.NewGraph(i.store, obj)
-
-	// decode decodes whatever is stored at path into the given value
-	decode := func(v interface{}, path ...string) (err error) {
-		defer func() {
-			if err != nil {
-				err = fmt.Errorf("error decoding %s into %T: %s", path, v, err)
-			}
-		}()
-		x, err := graph.Get(path...)
-		if meta.IsPathNotFound(err) {
-			return nil
-		} else if err != nil {
-			return err
-		}
-		id, ok := x.(*cid.Cid)
-		if !ok {
-			return fmt.Errorf("expected %s to be *cid.Cid, got %T", path, x)
-		}
-		obj, err := i.store.Get(id)
-		if err != nil {
-			return err
-		}
-		return obj.Decode(v)
+func (i *Indexer) indexMessageHeader(resolver *meta.Resolver, ernID *cid.Cid, obj *meta.Object) error {
+	var header struct {
+		MessageID     string   `meta:"MessageId/@value"`
+		ThreadID      string   `meta:"MessageThreadId/@value"`
+		Created       string   `meta:"MessageCreatedDateTime/@value"`
+		Sender        *cid.Cid `meta:"MessageSender"`
+		SenderID      string   `meta:"MessageSender/PartyId/@value"`
+		SenderName    string   `meta:"MessageSender/PartyName/FullName/@value"`
+		Recipient     *cid.Cid `meta:"MessageRecipient"`
+		RecipientID   string   `meta:"MessageRecipient/PartyId/@value"`
+		RecipientName string   `meta:"MessageRecipient/PartyName/FullName/@value"`
+	}
+	if err := resolver.Graph(obj).DecodePath(&header); err != nil {
+		return err
 	}
 
 	// insert the MessageSender and MessageRecipient into the party index
-	insertParty := func(field string) (*cid.Cid, error) {
-		var id *cid.Cid
-		link, err := obj.GetLink(field)
-		if err == nil {
-			id = link.Cid
-		} else if !meta.IsPathNotFound(err) {
-			return nil, err
-		}
-		var partyID struct {
-			Value string `json:"@value"`
-		}
-		if err := decode(&partyID, field, "PartyId"); err != nil {
-			return nil, err
-		}
-		var partyName struct {
-			Value string `json:"@value"`
-		}
-		if err := decode(&partyName, field, "PartyName", "FullName"); err != nil {
-			return nil, err
-		}
-		_, err = i.db.Exec(
+	insertParty := func(id *cid.Cid, partyID, partyName string) error {
+		_, err := i.db.Exec(
 			"INSERT INTO party (cid, id, name) VALUES ($1, $2, $3)",
-			id.String(), partyID.Value, partyName.Value,
+			id.String(), partyID, partyName,
 		)
 		if err != nil && !isUniqueErr(err) {
-			return nil, err
+			return err
 		}
-		return id, nil
+		return nil
 	}
-	sender, err := insertParty("MessageSender")
-	if err != nil {
+	if err := insertParty(header.Sender, header.SenderID, header.SenderName); err != nil {
 		return err
 	}
-	recipient, err := insertParty("MessageRecipient")
-	if err != nil {
-		return err
-	}
-
-	// get the MessageId, MessageThreadId and MessageCreatedDateTime
-	// values
-	var messageID struct {
-		Value string `json:"@value"`
-	}
-	if err := decode(&messageID, "MessageId"); err != nil {
-		return err
-	}
-	var threadID struct {
-		Value string `json:"@value"`
-	}
-	if err := decode(&threadID, "MessageThreadId"); err != nil {
-		return err
-	}
-	var created struct {
-		Value string `json:"@value"`
-	}
-	if err := decode(&created, "MessageCreatedDateTime"); err != nil {
+	if err := insertParty(header.Recipient, header.RecipientID, header.RecipientName); err != nil {
 		return err
 	}
 
 	// update the ERN index
-	_, err = i.db.Exec(
+	_, err := i.db.Exec(
 		"INSERT INTO ern (cid, message_id, thread_id, sender_id, recipient_id, created) VALUES ($1, $2, $3, $4, $5, $6)",
-		ernID.String(), messageID.Value, threadID.Value, sender.String(), recipient.String(), created.Value,
+		ernID.String(), header.MessageID, header.ThreadID, header.Sender.String(), header.Recipient.String(), header.Created,
 	)
 	return err
 }
 
-func (i *Indexer) indexWorkList(ernID *cid.Cid, obj *meta.Object) error {
+func (i *Indexer) indexWorkList(resolver *meta.Resolver, ernID *cid.Cid, obj *meta.Object) error {
 	// TODO: index MusicalWorks
 	return nil
 }
 
 // indexResourceList indexes an ERN ResourceList based on SoundRecordings.
-func (i *Indexer) indexResourceList(ernID *cid.Cid, obj *meta.Object) error {
+func (i *Indexer) indexResourceList(resolver *meta.Resolver, ernID *cid.Cid, obj *meta.Object) error {
 	// the SoundRecording property can either be a link if there is only
 	// one SoundRecording in the list, or an array of links if there are
-	// multiple SoundRecordings in the list, so we need to handle both
-	// cases
-	v, err := obj.Get("SoundRecording")
+	// multiple SoundRecordings in the list, both of which the '*' selector
+	// matches
+	matches, err := resolver.Graph(obj).Select("SoundRecording/*")
 	if err != nil {
 		return err
 	}
-	var cids []*cid.Cid
-	switch v := v.(type) {
-	case *format.Link:
-		cids = []*cid.Cid{v.Cid}
-	case []interface{}:
-		for _, x := range v {
-			cid, ok := x.(*cid.Cid)
-			if !ok {
-				return fmt.Errorf("invalid resource type %T, expected *cid.Cid", x)
-			}
-			cids = append(cids, cid)
-		}
-	}
 
-	// load and index each SoundRecording link
-	for _, cid := range cids {
-		obj, err := i.store.Get(cid)
-		if err != nil {
-			return err
+	// load the SoundRecordings concurrently and index each one
+	cids := make([]*cid.Cid, len(matches))
+	for n, m := range matches {
+		id, ok := m.Value.(*cid.Cid)
+		if !ok {
+			return fmt.Errorf("invalid resource type %T, expected *cid.Cid", m.Value)
 		}
-		if err := i.indexSoundRecording(ernID, obj); err != nil {
+		cids[n] = id
+	}
+	objs, err := resolver.GetAll(cids)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := i.indexSoundRecording(resolver, ernID, obj); err != nil {
 			return err
 		}
 	}
@@ -265,19 +225,24 @@ func (i *Indexer) indexResourceList(ernID *cid.Cid, obj *meta.Object) error {
 
 // indexSoundRecording indexes an ERN SoundRecording based on its ID (either an
 // ISRC, CatalogNumber or ProprietaryId) and its ReferenceTitle.
-func (i *Indexer) indexSoundRecording(ernID *cid.Cid, obj *meta.Object) error {
-	graph := meta.NewGraph(i.store, obj)
+func (i *Indexer) indexSoundRecording(resolver *meta.Resolver, ernID *cid.Cid, obj *meta.Object) error {
+	graph := resolver.Graph(obj)
 
-	// load each potential ID separately
+	// load each potential ID separately, allowing for multiple
+	// SoundRecordingIds each with multiple ProprietaryIds
 	var ids []string
 	for _, field := range []string{"ISRC", "CatalogNumber", "ProprietaryId"} {
-		v, err := graph.Get("SoundRecordingId", field, "@value")
-		if meta.IsPathNotFound(err) {
-			continue
-		} else if err != nil {
+		matches, err := graph.Select("SoundRecordingId/*/" + field + "/*/@value")
+		if err != nil {
 			return err
 		}
-		ids = append(ids, v.(string))
+		for _, m := range matches {
+			id, ok := m.Value.(string)
+			if !ok {
+				return fmt.Errorf("invalid %s type %T, expected string", field, m.Value)
+			}
+			ids = append(ids, id)
+		}
 	}
 
 	// load the ReferenceTitle
@@ -316,7 +281,7 @@ func (i *Indexer) indexSoundRecording(ernID *cid.Cid, obj *meta.Object) error {
 	return nil
 }
 
-func (i *Indexer) indexReleaseList(ernID *cid.Cid, obj *meta.Object) error {
+func (i *Indexer) indexReleaseList(resolver *meta.Resolver, ernID *cid.Cid, obj *meta.Object) error {
 	// TODO: index Releases
 	return nil
 }
@@ -19,7 +19,10 @@
 
 package ern
 
-import "github.com/meta-network/go-meta/migrate"
+import (
+	"github.com/meta-network/go-meta/claimindex"
+	"github.com/meta-network/go-meta/migrate"
+)
 
 // migrations is a set of database migrations to run on a SQLite3 database
 // to prepare it for indexing a META stream of ERNs.
@@ -209,4 +212,8 @@ CREATE INDEX sound_recording_contributor_party_idx ON sound_recording_contributo
 CREATE UNIQUE INDEX sound_recording_contributor_unique_idx ON sound_recording_contributor (sound_recording_id, party_id);
 `,
 	)
+
+	// migration 2 creates an index of the signers of claims about
+	// indexed ERNs so that they can be filtered by trusted sources
+	migrations.Add(2, claimindex.Migration)
 }
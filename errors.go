@@ -71,3 +71,35 @@ type ErrCidMismatch struct {
 func (e ErrCidMismatch) Error() string {
 	return fmt.Sprintf("meta: CID mismatch, expected %q, got %q", e.Expected, e.Actual)
 }
+
+type ErrRefNotFound struct {
+	Name string
+}
+
+func (e ErrRefNotFound) Error() string {
+	return fmt.Sprintf("meta: ref not found: %s", e.Name)
+}
+
+func IsRefNotFound(err error) bool {
+	_, ok := err.(ErrRefNotFound)
+	return ok
+}
+
+type ErrRefConflict struct {
+	Name     string
+	Expected *cid.Cid
+	Actual   *cid.Cid
+}
+
+func (e ErrRefConflict) Error() string {
+	return fmt.Sprintf("meta: ref %s has changed, expected %s, got %s", e.Name, e.Expected, e.Actual)
+}
+
+type ErrInvalidSelector struct {
+	Selector string
+	Reason   string
+}
+
+func (e ErrInvalidSelector) Error() string {
+	return fmt.Sprintf("meta: invalid selector %q: %s", e.Selector, e.Reason)
+}
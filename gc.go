@@ -0,0 +1,281 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// pinsPrefix is the datastore namespace which pins are stored under.
+var pinsPrefix = datastore.NewKey("pins")
+
+// Pin pins the object with the given CID using the given name so that it,
+// and every object reachable from it, is kept when the store is garbage
+// collected.
+//
+// Pinning an existing name replaces the CID it points at.
+func (s *Store) Pin(name string, cid *cid.Cid) error {
+	key, err := s.pinKey(name)
+	if err != nil {
+		return err
+	}
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
+	if has, err := s.Has(cid); err != nil {
+		return err
+	} else if !has {
+		return fmt.Errorf("meta: cannot pin %s, object not found", cid)
+	}
+	return s.store.Put(key, cid.Bytes())
+}
+
+// Unpin removes the pin with the given name.
+func (s *Store) Unpin(name string) error {
+	key, err := s.pinKey(name)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(key); err == datastore.ErrNotFound {
+		return fmt.Errorf("meta: pin not found: %s", name)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Pins returns all pinned CIDs keyed by pin name.
+func (s *Store) Pins() (map[string]*cid.Cid, error) {
+	results, err := s.store.Query(query.Query{Prefix: pinsPrefix.String()})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	pins := make(map[string]*cid.Cid, len(entries))
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if !pinsPrefix.IsAncestorOf(key) {
+			continue
+		}
+		value := entry.Value
+		if _, ok := value.([]byte); !ok {
+			// some datastores do not return values from
+			// queries so load it explicitly
+			value, err = s.store.Get(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		data, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("meta: unexpected value type for pin %s: %T", key, value)
+		}
+		id, err := cid.Cast(data)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(key.String(), pinsPrefix.String()+"/")
+		pins[name] = id
+	}
+	return pins, nil
+}
+
+// pinKey returns the datastore key to store the pin with the given name.
+func (s *Store) pinKey(name string) (datastore.Key, error) {
+	key := pinsPrefix.Child(datastore.NewKey(name))
+	if name == "" || key.Equal(pinsPrefix) {
+		return datastore.Key{}, fmt.Errorf("meta: invalid pin name: %q", name)
+	}
+	return key, nil
+}
+
+// GCOptions are options which control how Store.GC removes objects.
+type GCOptions struct {
+	// DryRun determines whether to just report the unreachable objects
+	// without deleting them.
+	DryRun bool
+}
+
+// GCResult is the result of garbage collecting a store.
+type GCResult struct {
+	// Removed are the CIDs of objects which were removed (or would be
+	// removed in a dry run).
+	Removed []*cid.Cid
+
+	// Size is the total size in bytes of the removed objects.
+	Size int64
+}
+
+// GC performs a mark-and-sweep garbage collection of the store, deleting all
+// objects which are not reachable from the given root CIDs or from the
+// store's pins, refs, ref logs and claims (objects with type ClaimType).
+//
+// Links to objects which are not in the store are ignored whilst marking.
+//
+// Objects, pins and refs cannot be written to the store whilst it is being
+// collected. If the store's datastore is a TxnDatastore (e.g. the sqlite
+// datastore) then the collection runs in a transaction which also stops
+// other processes writing to it, otherwise the store must not be written to
+// by other processes during the collection.
+func (s *Store) GC(ctx context.Context, pins []*cid.Cid, opts *GCOptions) (*GCResult, error) {
+	if opts == nil {
+		opts = &GCOptions{}
+	}
+
+	s.gcMtx.Lock()
+	defer s.gcMtx.Unlock()
+
+	result := &GCResult{}
+	err := s.txn(func(ds datastore.Datastore) error {
+		roots, err := s.gcRoots()
+		if err != nil {
+			return err
+		}
+		marked, err := s.mark(ctx, append(roots, pins...))
+		if err != nil {
+			return err
+		}
+
+		// collect the unmarked objects before deleting anything so
+		// that the datastore is not modified whilst it is being
+		// iterated
+		iter, err := s.AllCids(ctx)
+		if err != nil {
+			return err
+		}
+		defer iter.Close()
+		for iter.Next() {
+			id := iter.Cid()
+			if _, ok := marked[id.KeyString()]; ok {
+				continue
+			}
+			data, err := s.store.Get(s.key(id))
+			if err != nil {
+				return err
+			}
+			if v, ok := data.([]byte); ok {
+				result.Size += int64(len(v))
+			}
+			result.Removed = append(result.Removed, id)
+		}
+		if err := iter.Err(); err != nil {
+			return err
+		}
+
+		// sweep the unmarked objects
+		if opts.DryRun {
+			return nil
+		}
+		for _, id := range result.Removed {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			keys, err := s.typeIndexKeys(id)
+			if err != nil {
+				return err
+			}
+			for _, key := range append(keys, s.key(id)) {
+				if err := ds.Delete(key); err != nil && err != datastore.ErrNotFound {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return result, nil
+	}
+	for _, id := range result.Removed {
+		s.cache.remove(id)
+	}
+	if err := s.updateIndexes(nil, result.Removed); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// gcRoots returns the CIDs of the store's pins, refs, ref log entries and
+// claims, which are kept when the store is garbage collected.
+func (s *Store) gcRoots() ([]*cid.Cid, error) {
+	pins, err := s.Pins()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := s.Refs().List()
+	if err != nil {
+		return nil, err
+	}
+	roots, err := s.Refs().logCids()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range pins {
+		roots = append(roots, id)
+	}
+	for _, id := range refs {
+		roots = append(roots, id)
+	}
+	claims, err := s.FindByType(ClaimType, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return append(roots, claims...), nil
+}
+
+// mark returns the keys of every object reachable from the given roots.
+func (s *Store) mark(ctx context.Context, roots []*cid.Cid) (map[string]struct{}, error) {
+	marked := make(map[string]struct{})
+	for _, root := range roots {
+		if _, ok := marked[root.KeyString()]; ok {
+			continue
+		}
+		obj, err := s.Get(root)
+		if err == datastore.ErrNotFound {
+			// ref logs can refer to objects which have already
+			// been removed
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("meta: error loading root object %s: %s", root, err)
+		}
+		err = NewGraph(s, obj).Walk(ctx, func(_ []string, obj *Object, _ int) error {
+			key := obj.Cid().KeyString()
+			if _, ok := marked[key]; ok {
+				return SkipLinks
+			}
+			marked[key] = struct{}{}
+			return nil
+		}, &WalkOptions{IgnoreMissing: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return marked, nil
+}
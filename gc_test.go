@@ -0,0 +1,161 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestGC(t *testing.T) {
+	graph := testGraph(t)
+	store := graph.store
+
+	// store some garbage
+	garbage := []*Object{
+		MustEncode(map[string]string{"name": "garbage0"}),
+		MustEncode(map[string]string{"name": "garbage1"}),
+	}
+	var garbageSize int64
+	for _, obj := range garbage {
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		garbageSize += int64(len(obj.RawData()))
+	}
+
+	// pin the root of the graph and check it is returned from Pins
+	if err := store.Pin("family", graph.Root().Cid()); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := store.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 || !pins["family"].Equals(graph.Root().Cid()) {
+		t.Fatalf("unexpected pins: %v", pins)
+	}
+
+	gc := func(dryRun bool) *GCResult {
+		res, err := store.GC(context.Background(), []*cid.Cid{pins["family"]}, &GCOptions{DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Removed) != len(garbage) || res.Size != garbageSize {
+			t.Fatalf("expected GC to remove %d objects (%d bytes), got %d (%d bytes)", len(garbage), garbageSize, len(res.Removed), res.Size)
+		}
+		return res
+	}
+
+	// check a dry run doesn't remove anything
+	gc(true)
+	stat, err := store.Stat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Count != 6 {
+		t.Fatalf("expected dry run to keep 6 objects, got %d", stat.Count)
+	}
+
+	// check a real run removes the garbage but keeps the graph
+	gc(false)
+	for _, obj := range garbage {
+		if has, err := store.Has(obj.Cid()); err != nil {
+			t.Fatal(err)
+		} else if has {
+			t.Fatalf("expected %s to be removed", obj.Cid())
+		}
+	}
+	stat, err = store.Stat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Count != 4 {
+		t.Fatalf("expected 4 objects after GC, got %d", stat.Count)
+	}
+
+	// check unpinning makes the whole graph garbage
+	if err := store.Unpin("family"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := store.GC(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Removed) != 4 {
+		t.Fatalf("expected 4 objects to be removed, got %d", len(res.Removed))
+	}
+}
+
+// TestGCRoots tests that GC keeps objects which are reachable from refs, ref
+// logs and claims even though they are not pinned.
+func TestGCRoots(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(name string) *Object {
+		obj := MustEncode(map[string]string{"name": name})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	old := put("old")
+	current := put("current")
+	subject := put("subject")
+	garbage := put("garbage")
+
+	// point a ref at old then current so that old is only in the ref log
+	if err := store.Refs().Set("test", old.Cid(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Refs().Set("test", current.Cid(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// sign the subject
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := Sign(subject.Cid(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(claim); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := store.GC(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Removed) != 1 || !res.Removed[0].Equals(garbage.Cid()) {
+		t.Fatalf("expected GC to only remove %s, got %v", garbage.Cid(), res.Removed)
+	}
+	for _, obj := range []*Object{old, current, subject, claim} {
+		if has, err := store.Has(obj.Cid()); err != nil {
+			t.Fatal(err)
+		} else if !has {
+			t.Fatalf("expected %s to be kept", obj.Cid())
+		}
+	}
+}
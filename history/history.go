@@ -0,0 +1,95 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+// The history package provides GraphQL resolvers for the revision history of
+// META objects which are shared by the GraphQL APIs of the indexes.
+package history
+
+import (
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/meta-network/go-meta"
+)
+
+// RevisionSchema is the GraphQL schema of the Revision type resolved by
+// RevisionResolver, to be included in the schemas of APIs which have
+// history fields.
+const RevisionSchema = `
+type Revision {
+  cid:    String!
+  author: String
+  time:   String
+  reason: String
+}
+`
+
+// Resolvers returns resolvers for the revision history of the object with
+// the given CID, most recent first (see meta.History).
+func Resolvers(store *meta.Store, objectID string) ([]*RevisionResolver, error) {
+	id, err := cid.Parse(objectID)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := meta.History(store, id)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*RevisionResolver, len(objs))
+	for i, obj := range objs {
+		rev, err := obj.Revision()
+		if err != nil {
+			return nil, err
+		}
+		resolvers[i] = &RevisionResolver{obj.Cid().String(), rev}
+	}
+	return resolvers, nil
+}
+
+// RevisionResolver defines GraphQL resolver functions for revision fields.
+type RevisionResolver struct {
+	cid      string
+	revision *meta.Revision
+}
+
+func (r *RevisionResolver) Cid() string {
+	return r.cid
+}
+
+func (r *RevisionResolver) Author() *string {
+	if r.revision == nil {
+		return nil
+	}
+	return &r.revision.Author
+}
+
+func (r *RevisionResolver) Time() *string {
+	if r.revision == nil {
+		return nil
+	}
+	t := r.revision.Time.Format(time.RFC3339)
+	return &t
+}
+
+func (r *RevisionResolver) Reason() *string {
+	if r.revision == nil {
+		return nil
+	}
+	return &r.revision.Reason
+}
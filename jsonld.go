@@ -0,0 +1,355 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// contextKey is the property which contains an object's JSON-LD context (see
+// https://www.w3.org/TR/json-ld/#the-context).
+const contextKey = "@context"
+
+// Context is a JSON-LD context which maps terms to IRIs.
+//
+// Term values may themselves be compact IRIs (e.g. "ern:NewReleaseMessage"
+// where "ern" is also a term), which is how contexts generated by
+// 'meta import xsd' map type and element names to their XML namespace.
+type Context map[string]string
+
+// LoadContext loads the JSON-LD context represented by v, which is either a
+// link to an object with a @context property, an inline map of terms to IRIs
+// (or to term definitions with an @id), or a list of contexts which are
+// merged in order.
+func LoadContext(store *Store, v interface{}) (Context, error) {
+	ctx := make(Context)
+	if err := newExpander(store).loadContext(ctx, v, 0); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// maxContextDepth limits how deeply contexts can link to other contexts,
+// which prevents cyclic contexts from being loaded forever.
+const maxContextDepth = 16
+
+// expander expands objects using their JSON-LD contexts, caching linked
+// contexts so that objects which share a context (e.g. all the objects
+// converted from an ERN) only load it once.
+type expander struct {
+	store *Store
+
+	// ignoreMissing determines whether to ignore linked contexts which
+	// are not in the store rather than returning an error.
+	ignoreMissing bool
+
+	contexts map[string]Context
+}
+
+func newExpander(store *Store) *expander {
+	return &expander{store: store, contexts: make(map[string]Context)}
+}
+
+// loadContext loads the context represented by v into ctx.
+func (e *expander) loadContext(ctx Context, v interface{}, depth int) error {
+	if depth > maxContextDepth {
+		return fmt.Errorf("meta: JSON-LD context exceeds maximum depth of %d", maxContextDepth)
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case *cid.Cid:
+		linked, ok := e.contexts[v.KeyString()]
+		if !ok {
+			obj, err := e.store.Get(v)
+			if err == datastore.ErrNotFound && e.ignoreMissing {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			props, err := obj.Properties()
+			if err != nil {
+				return err
+			}
+			x, ok := props[contextKey]
+			if !ok {
+				return fmt.Errorf("meta: JSON-LD context %s has no @context property", v)
+			}
+			linked = make(Context)
+			if err := e.loadContext(linked, x, depth+1); err != nil {
+				return err
+			}
+			e.contexts[v.KeyString()] = linked
+		}
+		for term, iri := range linked {
+			ctx[term] = iri
+		}
+		return nil
+	case []interface{}:
+		for _, x := range v {
+			if err := e.loadContext(ctx, x, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		for term, x := range v {
+			switch x := x.(type) {
+			case string:
+				ctx[term] = x
+			case map[string]interface{}:
+				if id, ok := x["@id"].(string); ok {
+					ctx[term] = id
+				}
+			case nil:
+				delete(ctx, term)
+			}
+		}
+		return nil
+	case map[string]string:
+		for term, x := range v {
+			ctx[term] = x
+		}
+		return nil
+	case string:
+		return fmt.Errorf("meta: remote JSON-LD context %q is not supported", v)
+	default:
+		return fmt.Errorf("meta: invalid JSON-LD context of type %T", v)
+	}
+}
+
+// clone returns a copy of the context.
+func (c Context) clone() Context {
+	ctx := make(Context, len(c))
+	for term, iri := range c {
+		ctx[term] = iri
+	}
+	return ctx
+}
+
+// ExpandIRI expands a term or compact IRI into an absolute IRI, returning
+// the given value unchanged if it is a keyword or cannot be expanded.
+func (c Context) ExpandIRI(s string) string {
+	for i := 0; i < maxContextDepth; i++ {
+		if strings.HasPrefix(s, "@") {
+			return s
+		}
+		if iri, ok := c[s]; ok && iri != s {
+			s = iri
+			continue
+		}
+		prefix, suffix, ok := splitCompactIRI(s)
+		if !ok {
+			return s
+		}
+		iri, ok := c[prefix]
+		if !ok || iri == prefix {
+			return s
+		}
+		s = iri + suffix
+	}
+	return s
+}
+
+// CompactIRI compacts an absolute IRI into a term if the context has a term
+// for it, into a compact IRI if the context has a term for a prefix of it,
+// and otherwise returns it unchanged.
+//
+// If more than one term matches, the shortest (and then lexically least) is
+// used so that the result is deterministic.
+func (c Context) CompactIRI(iri string) string {
+	if strings.HasPrefix(iri, "@") {
+		return iri
+	}
+	var term, prefix, prefixIRI string
+	for _, t := range c.terms() {
+		expanded := c.ExpandIRI(t)
+		if expanded == iri {
+			if term == "" || len(t) < len(term) {
+				term = t
+			}
+			continue
+		}
+		if strings.Contains(t, ":") || !strings.HasPrefix(iri, expanded) {
+			continue
+		}
+		// prefer the prefix which matches the longest part of the IRI
+		if len(expanded) > len(prefixIRI) || (len(expanded) == len(prefixIRI) && len(t) < len(prefix)) {
+			prefix, prefixIRI = t, expanded
+		}
+	}
+	switch {
+	case term != "":
+		return term
+	case prefix != "":
+		return prefix + ":" + iri[len(prefixIRI):]
+	default:
+		return iri
+	}
+}
+
+// terms returns the context's terms in lexical order.
+func (c Context) terms() []string {
+	terms := make([]string, 0, len(c))
+	for term := range c {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// splitCompactIRI splits a compact IRI like "prefix:suffix" into its prefix
+// and suffix, returning false if s is not a compact IRI (e.g. if it is an
+// absolute IRI like "http://example.com").
+func splitCompactIRI(s string) (string, string, bool) {
+	i := strings.Index(s, ":")
+	if i <= 0 || strings.HasPrefix(s[i+1:], "//") {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+// Expand returns the properties of the object with property names and @type
+// values expanded to absolute IRIs using the object's JSON-LD context, which
+// is loaded from the store if it is linked.
+//
+// The @context property is removed, nested maps are expanded using the
+// object's context merged with any context they define, and links are left
+// as links. Terms which are not defined by the context are left unchanged
+// rather than being dropped.
+func Expand(store *Store, obj *Object) (map[string]interface{}, error) {
+	return newExpander(store).expand(obj)
+}
+
+func (e *expander) expand(obj *Object) (map[string]interface{}, error) {
+	props, err := obj.Properties()
+	if err != nil {
+		return nil, err
+	}
+	v, err := e.expandValue(make(Context), props)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+func (e *expander) expandValue(ctx Context, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if local, ok := v[contextKey]; ok {
+			ctx = ctx.clone()
+			if err := e.loadContext(ctx, local, 0); err != nil {
+				return nil, err
+			}
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, x := range v {
+			switch key {
+			case contextKey:
+				continue
+			case "@type":
+				out[key] = mapTypes(x, ctx.ExpandIRI)
+				continue
+			}
+			y, err := e.expandValue(ctx, x)
+			if err != nil {
+				return nil, err
+			}
+			out[ctx.ExpandIRI(key)] = y
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, x := range v {
+			y, err := e.expandValue(ctx, x)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = y
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// Compact compacts expanded properties (as returned by Expand) using the
+// given JSON-LD context, which is loaded in the same way as LoadContext and
+// is set as the @context property of the result.
+func Compact(store *Store, expanded map[string]interface{}, context interface{}) (map[string]interface{}, error) {
+	ctx, err := LoadContext(store, context)
+	if err != nil {
+		return nil, err
+	}
+	out := compactValue(ctx, expanded).(map[string]interface{})
+	if context != nil {
+		out[contextKey] = context
+	}
+	return out, nil
+}
+
+func compactValue(ctx Context, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, x := range v {
+			if key == "@type" {
+				out[key] = mapTypes(x, ctx.CompactIRI)
+				continue
+			}
+			out[ctx.CompactIRI(key)] = compactValue(ctx, x)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, x := range v {
+			out[i] = compactValue(ctx, x)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// mapTypes applies fn to a @type value, which is either a string or a list
+// of strings.
+func mapTypes(v interface{}, fn func(string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, x := range v {
+			if s, ok := x.(string); ok {
+				out[i] = fn(s)
+			} else {
+				out[i] = x
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,136 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestExpandCompact(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *Object {
+		obj := MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+
+	// a linked context like those generated by 'meta import xsd'
+	ernContext := put(map[string]interface{}{
+		"@context": map[string]interface{}{
+			"ern":               "http://ddex.net/xml/ern/382/",
+			"NewReleaseMessage": "ern:NewReleaseMessage",
+			"MessageHeader":     "ern:MessageHeader",
+		},
+	})
+	header := put(map[string]interface{}{"@type": "MessageHeader"})
+	obj := put(map[string]interface{}{
+		"@context":      []*cid.Cid{ernContext.Cid()},
+		"@type":         "NewReleaseMessage",
+		"MessageHeader": header.Cid(),
+		"ern:Other":     "other",
+		"unknown":       "unknown",
+		"nested": map[string]interface{}{
+			"@context":      map[string]interface{}{"name": map[string]interface{}{"@id": "http://schema.org/name"}},
+			"name":          map[string]interface{}{"@value": "nested"},
+			"MessageHeader": "inherited",
+		},
+	})
+
+	expanded, err := Expand(store, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"@type": "http://ddex.net/xml/ern/382/NewReleaseMessage",
+		"http://ddex.net/xml/ern/382/MessageHeader": header.Cid(),
+		"http://ddex.net/xml/ern/382/Other":         "other",
+		"unknown":                                   "unknown",
+		"nested": map[string]interface{}{
+			"http://schema.org/name":                    map[string]interface{}{"@value": "nested"},
+			"http://ddex.net/xml/ern/382/MessageHeader": "inherited",
+		},
+	}
+	if !reflect.DeepEqual(expanded, expected) {
+		t.Fatalf("unexpected expanded object:\nexpected: %v\ngot:      %v", expected, expanded)
+	}
+
+	// compacting with the object's context should produce the original
+	// object, except for the nested context which is not included
+	compacted, err := Compact(store, expanded, []interface{}{ernContext.Cid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := compacted["@type"]; v != "NewReleaseMessage" {
+		t.Fatalf("unexpected compacted @type: %v", v)
+	}
+	if v, ok := compacted["MessageHeader"].(*cid.Cid); !ok || !v.Equals(header.Cid()) {
+		t.Fatalf("unexpected compacted MessageHeader: %v", compacted["MessageHeader"])
+	}
+	if v := compacted["ern:Other"]; v != "other" {
+		t.Fatalf("unexpected compacted ern:Other: %v", v)
+	}
+	if v := compacted["unknown"]; v != "unknown" {
+		t.Fatalf("unexpected compacted unknown: %v", v)
+	}
+	nested := compacted["nested"].(map[string]interface{})
+	if v := nested["http://schema.org/name"]; v == nil {
+		t.Fatalf("expected nested name to remain expanded, got %v", nested)
+	}
+
+	// check an inline context round trips to the same CID
+	artist := put(map[string]interface{}{
+		"@context": map[string]interface{}{
+			"name": "https://musicbrainz.org/doc/Artist#Name",
+			"ipi":  "https://musicbrainz.org/doc/Artist#IPI_code",
+		},
+		"name": "Artist",
+		"ipi":  []interface{}{"00052210040"},
+	})
+	expanded, err = Expand(store, artist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := expanded["https://musicbrainz.org/doc/Artist#Name"]; v != "Artist" {
+		t.Fatalf("unexpected expanded name: %v", v)
+	}
+	props, err := artist.Properties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compacted, err = Compact(store, expanded, props["@context"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj := MustEncode(compacted); !obj.Cid().Equals(artist.Cid()) {
+		t.Fatalf("expected compacted object to have CID %s, got %s", artist.Cid(), obj.Cid())
+	}
+
+	// check remote contexts are rejected
+	remote := put(map[string]interface{}{"@context": "http://schema.org/"})
+	if _, err := Expand(store, remote); err == nil {
+		t.Fatal("expected error expanding object with remote context")
+	}
+}
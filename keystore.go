@@ -0,0 +1,282 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/satori/go.uuid"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// keyStoreIterations is the number of PBKDF2 iterations used to derive the
+// encryption key of a key file from its passphrase.
+const keyStoreIterations = 262144
+
+// keyStoreKeyLen is the length of the key derived from the passphrase of a
+// key file, the first half of which is the AES-128 key and the second half
+// of which is used to compute the MAC.
+const keyStoreKeyLen = 32
+
+// ErrDecrypt is returned when a key file cannot be decrypted with the given
+// passphrase.
+var ErrDecrypt = errors.New("meta: could not decrypt key with given passphrase")
+
+// KeyStore stores passphrase encrypted keys as files in a directory, using
+// the Ethereum version 3 key file format with a PBKDF2 key derivation
+// function so that the files can also be used with Ethereum tools.
+type KeyStore struct {
+	dir string
+}
+
+// NewKeyStore returns a KeyStore which stores key files in the given
+// directory.
+func NewKeyStore(dir string) *KeyStore {
+	return &KeyStore{dir: dir}
+}
+
+// NewKey generates a new key and stores it encrypted with the given
+// passphrase.
+func (ks *KeyStore) NewKey(passphrase string) (*Key, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.StoreKey(key, passphrase); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// StoreKey stores the given key encrypted with the given passphrase.
+func (ks *KeyStore) StoreKey(key *Key, passphrase string) error {
+	data, err := encryptKey(key, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("UTC--%s--%x", time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), key.Address().Bytes())
+	return ioutil.WriteFile(filepath.Join(ks.dir, name), data, 0600)
+}
+
+// Addresses returns the addresses of all the keys in the key store.
+func (ks *KeyStore) Addresses() ([]Address, error) {
+	files, err := ks.files()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]Address, 0, len(files))
+	for addr := range files {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].String() < addresses[j].String()
+	})
+	return addresses, nil
+}
+
+// Key loads the key with the given address, decrypting it with the given
+// passphrase.
+func (ks *KeyStore) Key(address Address, passphrase string) (*Key, error) {
+	files, err := ks.files()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := files[address]
+	if !ok {
+		return nil, fmt.Errorf("meta: key not found: %s", address)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := decryptKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if key.Address() != address {
+		return nil, fmt.Errorf("meta: key file %s contains key for %s, not %s", path, key.Address(), address)
+	}
+	return key, nil
+}
+
+// files returns the paths of the key files in the key store keyed by
+// address.
+func (ks *KeyStore) files() (map[Address]string, error) {
+	entries, err := ioutil.ReadDir(ks.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	files := make(map[Address]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(ks.dir, entry.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var v struct {
+			Address string `json:"address"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			continue
+		}
+		addr, err := ParseAddress(v.Address)
+		if err != nil {
+			continue
+		}
+		files[addr] = path
+	}
+	return files, nil
+}
+
+// keyFile is the JSON representation of an Ethereum version 3 key file.
+type keyFile struct {
+	Address string        `json:"address"`
+	Crypto  keyFileCrypto `json:"crypto"`
+	ID      string        `json:"id"`
+	Version int           `json:"version"`
+}
+
+type keyFileCrypto struct {
+	Cipher       string `json:"cipher"`
+	CipherText   string `json:"ciphertext"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	KDF       string `json:"kdf"`
+	KDFParams struct {
+		C     int    `json:"c"`
+		DKLen int    `json:"dklen"`
+		PRF   string `json:"prf"`
+		Salt  string `json:"salt"`
+	} `json:"kdfparams"`
+	MAC string `json:"mac"`
+}
+
+// encryptKey encrypts the key with the given passphrase and returns it
+// encoded as a key file.
+func encryptKey(key *Key, passphrase string) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	derivedKey := pbkdf2.Key([]byte(passphrase), salt, keyStoreIterations, keyStoreKeyLen, sha256.New)
+	cipherText, err := aesCTR(derivedKey[:16], iv, key.PrivateKey())
+	if err != nil {
+		return nil, err
+	}
+
+	f := &keyFile{
+		Address: hex.EncodeToString(key.Address().Bytes()),
+		ID:      uuid.NewV4().String(),
+		Version: 3,
+	}
+	f.Crypto.Cipher = "aes-128-ctr"
+	f.Crypto.CipherText = hex.EncodeToString(cipherText)
+	f.Crypto.CipherParams.IV = hex.EncodeToString(iv)
+	f.Crypto.KDF = "pbkdf2"
+	f.Crypto.KDFParams.C = keyStoreIterations
+	f.Crypto.KDFParams.DKLen = keyStoreKeyLen
+	f.Crypto.KDFParams.PRF = "hmac-sha256"
+	f.Crypto.KDFParams.Salt = hex.EncodeToString(salt)
+	f.Crypto.MAC = hex.EncodeToString(keccak256(derivedKey[16:32], cipherText))
+	return json.MarshalIndent(f, "", "  ")
+}
+
+// decryptKey decrypts the given key file using the given passphrase.
+func decryptKey(data []byte, passphrase string) (*Key, error) {
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	c := &f.Crypto
+	if f.Version != 3 || c.Cipher != "aes-128-ctr" || c.KDF != "pbkdf2" || c.KDFParams.PRF != "hmac-sha256" {
+		return nil, errors.New("meta: unsupported key file format")
+	}
+	// only accept the KDF parameters written by encryptKey so that a
+	// crafted key file cannot make decryption take an arbitrary amount
+	// of time
+	if c.KDFParams.C != keyStoreIterations || c.KDFParams.DKLen != keyStoreKeyLen {
+		return nil, errors.New("meta: unsupported key file KDF parameters")
+	}
+	var hexErr error
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			hexErr = err
+		}
+		return b
+	}
+	salt := decode(c.KDFParams.Salt)
+	iv := decode(c.CipherParams.IV)
+	cipherText := decode(c.CipherText)
+	mac := decode(c.MAC)
+	if hexErr != nil {
+		return nil, fmt.Errorf("meta: invalid key file: %s", hexErr)
+	}
+	derivedKey := pbkdf2.Key([]byte(passphrase), salt, c.KDFParams.C, c.KDFParams.DKLen, sha256.New)
+	if !hmac.Equal(keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	privateKey, err := aesCTR(derivedKey[:16], iv, cipherText)
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(privateKey)
+}
+
+// aesCTR encrypts or decrypts the given data using AES in CTR mode.
+func aesCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, errors.New("meta: invalid IV length")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+	return out, nil
+}
@@ -20,12 +20,19 @@
 package meta
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/fs"
+	"github.com/ipfs/go-datastore/query"
 	"github.com/ipfs/go-ipld-format"
 	"github.com/lmars/go-ipld-cbor"
 )
@@ -141,6 +148,87 @@ func (o *Object) GetList(key string) ([]interface{}, error) {
 	return l, nil
 }
 
+// GetInt looks up the property with the given key, unwrapping it if it is
+// a @value object, and returns it as an integer, parsing it if it is a
+// string.
+func (o *Object) GetInt(key string) (int64, error) {
+	v, err := o.GetValue(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := toInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("key %q: %s", key, err)
+	}
+	return i, nil
+}
+
+// GetBool looks up the property with the given key, unwrapping it if it is
+// a @value object, and returns it as a boolean, parsing it if it is a
+// string.
+func (o *Object) GetBool(key string) (bool, error) {
+	v, err := o.GetValue(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := toBool(v)
+	if err != nil {
+		return false, fmt.Errorf("key %q: %s", key, err)
+	}
+	return b, nil
+}
+
+// GetTime looks up the property with the given key, unwrapping it if it is
+// a @value object, and parses it as an RFC 3339 date and time, or as a date
+// if it has no time component.
+func (o *Object) GetTime(key string) (time.Time, error) {
+	v, err := o.GetValue(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := toTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key %q: %s", key, err)
+	}
+	return t, nil
+}
+
+// GetLinks looks up the property with the given key and returns the CIDs it
+// links to, which is useful for properties which are a single link if there
+// is one value but a list of links if there are many.
+func (o *Object) GetLinks(key string) ([]*cid.Cid, error) {
+	v, err := o.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case *format.Link:
+		return []*cid.Cid{v.Cid}, nil
+	case []interface{}:
+		cids := make([]*cid.Cid, len(v))
+		for i, x := range v {
+			id, ok := x.(*cid.Cid)
+			if !ok {
+				return nil, fmt.Errorf("key %q has element of type %T, not *cid.Cid", key, x)
+			}
+			cids[i] = id
+		}
+		return cids, nil
+	default:
+		return nil, fmt.Errorf("key %q has type %T, not *format.Link or []interface{}", key, v)
+	}
+}
+
+// GetValue returns the property with the given key, returning the value of
+// the @value property if it is a value object (e.g. {"@value": "x"}).
+func (o *Object) GetValue(key string) (interface{}, error) {
+	v, err := o.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return unwrapValue(v), nil
+}
+
 // Get returns the property with the given key.
 func (o *Object) Get(key string) (interface{}, error) {
 	v, rest, err := o.node.Resolve([]string{key})
@@ -152,10 +240,78 @@ func (o *Object) Get(key string) (interface{}, error) {
 	return v, nil
 }
 
+// Properties returns all the object's properties as a generic map, with
+// links represented as *cid.Cid values.
+func (o *Object) Properties() (map[string]interface{}, error) {
+	v, _, err := o.node.Resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("meta: expected object to be a map, got %T", v)
+	}
+	return m, nil
+}
+
+// Link represents a link from a property of an object to another object.
+type Link struct {
+	// Path is the path of the property within the object which
+	// contains the link (e.g. ["children", "0"]).
+	Path []string
+
+	// Cid is the CID of the linked object.
+	Cid *cid.Cid
+}
+
+// Links returns all the links contained in the object, ordered by the path of
+// the property which contains them.
+func (o *Object) Links() ([]*Link, error) {
+	properties, err := o.Properties()
+	if err != nil {
+		return nil, err
+	}
+	var links []*Link
+	var collect func(path []string, v interface{})
+	collect = func(path []string, v interface{}) {
+		switch v := v.(type) {
+		case *cid.Cid:
+			links = append(links, &Link{Path: path, Cid: v})
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				collect(appendPath(path, key), v[key])
+			}
+		case []interface{}:
+			for i, x := range v {
+				collect(appendPath(path, strconv.Itoa(i)), x)
+			}
+		}
+	}
+	collect(nil, properties)
+	return links, nil
+}
+
+// appendPath returns a new path with the given key appended, leaving the
+// given path unmodified.
+func appendPath(path []string, key string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
+}
+
 // MarshalJSON implements the json.Marshaler interface by encoding the
-// underlying CBOR node.
+// object's properties as IPLD DAG-JSON (see DecodeJSON).
 func (o *Object) MarshalJSON() ([]byte, error) {
-	return o.node.MarshalJSON()
+	properties, err := o.Properties()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(toJSON(properties))
 }
 
 // Graph is used to traverse an object graph using a store and starting from
@@ -163,11 +319,15 @@ func (o *Object) MarshalJSON() ([]byte, error) {
 type Graph struct {
 	store *Store
 	root  *Object
+
+	// get loads linked objects, and is either store.Get or the Get
+	// method of a Resolver (see Resolver.Graph).
+	get func(*cid.Cid) (*Object, error)
 }
 
 // NewGraph returns a new Graph
 func NewGraph(store *Store, root *Object) *Graph {
-	return &Graph{store, root}
+	return &Graph{store: store, root: root, get: store.Get}
 }
 
 // Root returns the root object of the graph
@@ -199,17 +359,45 @@ func (g *Graph) Get(path ...string) (interface{}, error) {
 		return nil, fmt.Errorf("meta: expected link object, got %T", v)
 	}
 
-	obj, err := g.store.Get(link.Cid)
+	obj, err := g.get(link.Cid)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewGraph(g.store, obj).Get(rest...)
+	return (&Graph{store: g.store, root: obj, get: g.get}).Get(rest...)
 }
 
 // Store provides storage for objects.
 type Store struct {
 	store datastore.Datastore
+
+	// cache is an optional cache of decoded objects.
+	cache *objectCache
+
+	// refsMtx makes ref updates atomic.
+	refsMtx sync.Mutex
+
+	// gcMtx is held for writing whilst the store is garbage collected,
+	// and for reading by anything which writes objects, pins or refs so
+	// that nothing is written between marking and sweeping.
+	gcMtx sync.RWMutex
+
+	// indexes are updated as objects are put in and deleted from the
+	// store (see AddIndex).
+	indexesMtx sync.RWMutex
+	indexes    []Index
+}
+
+// Index is an index of objects which is kept up to date by a Store as
+// objects are put in and deleted from it (see Store.AddIndex).
+type Index interface {
+	// IndexObject adds an object which has been put in the store to the
+	// index.
+	IndexObject(obj *Object) error
+
+	// RemoveObject removes an object which has been deleted from the
+	// store from the index.
+	RemoveObject(id *cid.Cid) error
 }
 
 // NewFSStore returns a new FS Store which uses an underlying datastore.
@@ -223,21 +411,158 @@ func NewFSStore(dir string) (*Store, error) {
 
 // NewStore returns a new Store which uses an underlying datastore.
 func NewStore(store datastore.Datastore) *Store {
-	return &Store{store}
+	return &Store{store: store}
+}
+
+// NewCachedStore returns a new Store which uses an underlying datastore and
+// caches up to cacheSize recently used objects in memory, avoiding reading
+// and decoding the same objects repeatedly when traversing graphs.
+func NewCachedStore(store datastore.Datastore, cacheSize int) *Store {
+	return &Store{store: store, cache: newObjectCache(cacheSize)}
 }
 
 // Get gets an object from the store.
 func (s *Store) Get(cid *cid.Cid) (*Object, error) {
+	if obj := s.cache.get(cid); obj != nil {
+		return obj, nil
+	}
 	data, err := s.store.Get(s.key(cid))
 	if err != nil {
 		return nil, err
 	}
-	return NewObject(cid, data.([]byte))
+	obj, err := NewObject(cid, data.([]byte))
+	if err != nil {
+		return nil, err
+	}
+	s.cache.add(obj)
+	return obj, nil
 }
 
-// Put stores an object in the store.
+// Put stores an object in the store, adding it to the type index if it has
+// a @type in the same datastore batch as the object.
 func (s *Store) Put(obj *Object) error {
-	return s.store.Put(s.key(obj.Cid()), obj.RawData())
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
+	batch, err := s.datastoreBatch()
+	if err != nil {
+		return err
+	}
+	if err := s.putObject(batch, obj); err != nil {
+		return err
+	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+	return s.updateIndexes([]*Object{obj}, nil)
+}
+
+// Has returns whether an object with the given CID exists in the store.
+func (s *Store) Has(cid *cid.Cid) (bool, error) {
+	if s.cache.get(cid) != nil {
+		return true, nil
+	}
+	return s.store.Has(s.key(cid))
+}
+
+// Delete deletes the object with the given CID from the store, removing it
+// from the type index.
+func (s *Store) Delete(id *cid.Cid) error {
+	s.gcMtx.RLock()
+	defer s.gcMtx.RUnlock()
+	keys, err := s.typeIndexKeys(id)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil && err != datastore.ErrNotFound {
+			return err
+		}
+	}
+	s.cache.remove(id)
+	if err := s.store.Delete(s.key(id)); err != nil {
+		return err
+	}
+	return s.updateIndexes(nil, []*cid.Cid{id})
+}
+
+// AddIndex adds an index which is updated each time objects are put in or
+// deleted from the store, including when a batch is committed.
+//
+// Indexes are updated after the underlying datastore has been written to,
+// so if updating an index fails the write is not undone and the error is
+// returned to the caller, who can bring the index up to date from the
+// store's contents.
+func (s *Store) AddIndex(index Index) {
+	s.indexesMtx.Lock()
+	defer s.indexesMtx.Unlock()
+	s.indexes = append(s.indexes, index)
+}
+
+// updateIndexes updates the store's indexes with objects which have been
+// put in and deleted from the store.
+func (s *Store) updateIndexes(put []*Object, deleted []*cid.Cid) error {
+	s.indexesMtx.RLock()
+	defer s.indexesMtx.RUnlock()
+	for _, index := range s.indexes {
+		for _, obj := range put {
+			if err := index.IndexObject(obj); err != nil {
+				return err
+			}
+		}
+		for _, id := range deleted {
+			if err := index.RemoveObject(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AllCids returns an iterator over the CIDs of all objects in the store.
+//
+// Keys in the underlying datastore which are not CIDs (for example those
+// nested under a namespace) are skipped.
+func (s *Store) AllCids(ctx context.Context) (*CidIterator, error) {
+	results, err := s.store.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return &CidIterator{ctx: ctx, results: results}, nil
+}
+
+// StoreStat contains statistics about the objects in a store.
+type StoreStat struct {
+	// Count is the number of objects in the store.
+	Count int64
+
+	// Size is the total size in bytes of the objects in the store.
+	Size int64
+}
+
+// Stat returns the number of objects in the store and their total size.
+func (s *Store) Stat(ctx context.Context) (*StoreStat, error) {
+	iter, err := s.AllCids(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	stat := &StoreStat{}
+	for iter.Next() {
+		data, err := s.store.Get(s.key(iter.Cid()))
+		if err != nil {
+			return nil, err
+		}
+		v, ok := data.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("meta: unexpected value type for %s: %T", iter.Cid(), data)
+		}
+		stat.Count++
+		stat.Size += int64(len(v))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return stat, nil
 }
 
 // key generates the key to use to store and retrieve the object with the
@@ -246,6 +571,78 @@ func (s *Store) key(cid *cid.Cid) datastore.Key {
 	return datastore.NewKey(cid.String())
 }
 
+// CidIterator iterates over the CIDs of objects in a store, see
+// Store.AllCids.
+//
+// Typical usage would be:
+//
+//   iter, err := store.AllCids(ctx)
+//   if err != nil {
+//     return err
+//   }
+//   defer iter.Close()
+//   for iter.Next() {
+//     fmt.Println(iter.Cid())
+//   }
+//   if err := iter.Err(); err != nil {
+//     return err
+//   }
+//
+type CidIterator struct {
+	ctx     context.Context
+	results query.Results
+	cid     *cid.Cid
+	err     error
+}
+
+// Next advances the iterator to the next CID, returning false if there are
+// no more CIDs or an error occurred.
+func (i *CidIterator) Next() bool {
+	if i.err != nil {
+		return false
+	}
+	for {
+		select {
+		case res, ok := <-i.results.Next():
+			if !ok {
+				return false
+			}
+			if res.Error != nil {
+				i.err = res.Error
+				return false
+			}
+			key := datastore.NewKey(res.Key)
+			if len(key.List()) != 1 {
+				continue
+			}
+			id, err := cid.Decode(key.Name())
+			if err != nil {
+				continue
+			}
+			i.cid = id
+			return true
+		case <-i.ctx.Done():
+			i.err = i.ctx.Err()
+			return false
+		}
+	}
+}
+
+// Cid returns the current CID.
+func (i *CidIterator) Cid() *cid.Cid {
+	return i.cid
+}
+
+// Err returns any error which occurred during iteration.
+func (i *CidIterator) Err() error {
+	return i.err
+}
+
+// Close stops the iteration and releases any associated resources.
+func (i *CidIterator) Close() error {
+	return i.results.Close()
+}
+
 // cidV1 is the number which identifies a CID as being CIDv1.
 //
 // TODO: move this to the github.com/ipfs/go-cid.
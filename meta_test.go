@@ -21,14 +21,20 @@ package meta
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
 )
 
 func TestObjectJSON(t *testing.T) {
@@ -98,6 +104,118 @@ func TestEncodeDecode(t *testing.T) {
 	}
 }
 
+func TestEncodeWithPrefix(t *testing.T) {
+	v := map[string]string{"name": "hash"}
+	data := MustEncode(v).RawData()
+	blake2bSum := blake2b.Sum256(data)
+	sha3Sum := sha3.Sum256(data)
+	for _, test := range []struct {
+		hashType uint64
+		digest   []byte
+	}{
+		{Blake2b256, blake2bSum[:]},
+		{multihash.SHA3_256, sha3Sum[:]},
+	} {
+		obj, err := EncodeWithPrefix(v, NewPrefix(test.hashType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := multihash.Decode(obj.Cid().Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash.Code != test.hashType {
+			t.Fatalf("expected hash type %x, got %x", test.hashType, hash.Code)
+		}
+		if !bytes.Equal(hash.Digest, test.digest) {
+			t.Fatalf("unexpected digest for hash type %x: %x", test.hashType, hash.Digest)
+		}
+
+		// check the object can be read back from a store
+		store := NewStore(datastore.NewMapDatastore())
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Get(obj.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name, err := got.GetString("name"); err != nil {
+			t.Fatal(err)
+		} else if name != "hash" {
+			t.Fatalf("expected name %q, got %q", "hash", name)
+		}
+	}
+
+	// check an invalid codec is rejected
+	prefix := NewPrefix(multihash.SHA2_256)
+	prefix.Codec = cid.Raw
+	if _, err := EncodeWithPrefix(v, prefix); err == nil {
+		t.Fatal("expected error encoding with raw codec")
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	objs := []*Object{
+		MustEncode(map[string]string{"name": "obj0"}),
+		MustEncode(map[string]string{"name": "obj1"}),
+		MustEncode(map[string]string{"name": "obj2"}),
+	}
+	var size int64
+	for _, obj := range objs {
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		size += int64(len(obj.RawData()))
+	}
+
+	// check Has returns true for stored objects and false otherwise
+	if has, err := store.Has(objs[0].Cid()); err != nil {
+		t.Fatal(err)
+	} else if !has {
+		t.Fatalf("expected store to have %s", objs[0].Cid())
+	}
+	missing := MustEncode(map[string]string{"name": "missing"})
+	if has, err := store.Has(missing.Cid()); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatalf("expected store to not have %s", missing.Cid())
+	}
+
+	// check Stat returns the count and total size
+	stat, err := store.Stat(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Count != int64(len(objs)) || stat.Size != size {
+		t.Fatalf("unexpected stat, expected {%d %d}, got %+v", len(objs), size, stat)
+	}
+
+	// check Delete removes the object and AllCids returns the rest
+	if err := store.Delete(objs[0].Cid()); err != nil {
+		t.Fatal(err)
+	}
+	iter, err := store.AllCids(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	var ids []string
+	for iter.Next() {
+		ids = append(ids, iter.Cid().String())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	expected := []string{objs[1].Cid().String(), objs[2].Cid().String()}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("unexpected CIDs:\nexpected: %v\ngot:      %v", expected, ids)
+	}
+}
+
 func benchmarkEncode(n int, t *testing.B) {
 	type test struct {
 		String string `json:"string"`
@@ -113,6 +113,80 @@ func TestArtistAPI(t *testing.T) {
 	}
 }
 
+// TestArtistHistoryAPI tests querying the revision history of an artist via
+// the GraphQL API.
+func TestArtistHistoryAPI(t *testing.T) {
+	x, err := newTestIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.cleanup()
+
+	// revise an artist and point the index at the new revision
+	artist := x.artists[0]
+	prev, err := meta.Encode(artist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := meta.Revise(x.store, prev, map[string]interface{}{"disambiguation_comment": "revised"}, &meta.Revision{
+		Author: "alice",
+		Reason: "add comment",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := x.db.Exec("UPDATE artist SET object_id = ? WHERE name = ?", obj.Cid().String(), artist.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newTestAPI(x.db, x.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	query := fmt.Sprintf(`{ artist(name:%q) { history { cid author reason } } }`, artist.Name)
+	data, _ := json.Marshal(map[string]string{"query": query})
+	res, err := http.Post(s.URL+"/graphql", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var r graphql.Response
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors in API response: %v", r.Errors)
+	}
+	type revision struct {
+		Cid    string  `json:"cid"`
+		Author *string `json:"author"`
+		Reason *string `json:"reason"`
+	}
+	var a struct {
+		Artists []struct {
+			History []revision `json:"history"`
+		} `json:"artist"`
+	}
+	if err := json.Unmarshal(r.Data, &a); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Artists) != 1 {
+		t.Fatalf("expected 1 artist, got %d", len(a.Artists))
+	}
+	history := a.Artists[0].History
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(history))
+	}
+	if history[0].Cid != obj.Cid().String() || history[0].Author == nil || *history[0].Author != "alice" || *history[0].Reason != "add comment" {
+		t.Fatalf("unexpected latest revision: %+v", history[0])
+	}
+	if history[1].Cid != prev.Cid().String() || history[1].Author != nil {
+		t.Fatalf("unexpected original revision: %+v", history[1])
+	}
+}
+
 func newTestAPI(db *sql.DB, store *meta.Store) (*httptest.Server, error) {
 	api, err := NewAPI(db, store)
 	if err != nil {
@@ -44,9 +44,18 @@ func NewConverter(db *sql.DB, store *meta.Store) *Converter {
 	}
 }
 
+// artistBatchSize is the number of artists ConvertArtists stores in each
+// batch.
+const artistBatchSize = 1000
+
 // ConvertArtists reads all artists from the database, converts them to META
 // objects, stores them in the META store and sends their CIDs to the given
 // stream.
+//
+// The objects are stored in batches of artistBatchSize rather than in a
+// single batch because the artists are streamed to indexers as they are
+// converted, and an indexer can only read objects from the store once the
+// batch containing them has been committed.
 func (c *Converter) ConvertArtists(ctx context.Context, outStream chan *cid.Cid) error {
 	// get all artists from the db
 	rows, err := c.db.Query(artistsQuery)
@@ -55,6 +64,35 @@ func (c *Converter) ConvertArtists(ctx context.Context, outStream chan *cid.Cid)
 	}
 	defer rows.Close()
 
+	batch, err := c.store.Batch()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if batch != nil {
+			batch.Rollback()
+		}
+	}()
+	var pending []*cid.Cid
+
+	// flush commits the current batch, sends the CIDs of the objects it
+	// contains to the output stream and starts a new batch
+	flush := func() error {
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+		for _, id := range pending {
+			select {
+			case outStream <- id:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		pending = nil
+		batch, err = c.store.Batch()
+		return err
+	}
+
 	for rows.Next() {
 		// read the db row into an Artist struct, handling nullable
 		// columns
@@ -123,17 +161,19 @@ func (c *Converter) ConvertArtists(ctx context.Context, outStream chan *cid.Cid)
 		if err != nil {
 			return err
 		}
-		if err := c.store.Put(obj); err != nil {
+		if err := batch.Put(obj); err != nil {
 			return err
 		}
-
-		// send the object's CID to the output stream
-		select {
-		case outStream <- obj.Cid():
-		case <-ctx.Done():
-			return ctx.Err()
+		pending = append(pending, obj.Cid())
+		if len(pending) == artistBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-	return rows.Err()
+	return flush()
 }
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ipfs/go-cid"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/history"
 )
 
 // GraphQLSchema is the GraphQL schema for the MusicBrainz META index.
@@ -56,8 +57,9 @@ type Artist {
   mbid:                   String!
   disambiguation_comment: String
   annotation:             [String!]
+  history:                [Revision!]!
 }
-`
+` + history.RevisionSchema
 
 // Resolver defines GraphQL resolver functions for the schema contained in
 // the GraphQLSchema constant, retrieving data from a META store and SQLite3
@@ -118,7 +120,7 @@ func (g *Resolver) Artist(args artistArgs) ([]*artistResolver, error) {
 		if err := obj.Decode(&artist); err != nil {
 			return nil, err
 		}
-		resolvers = append(resolvers, &artistResolver{objectID, &artist})
+		resolvers = append(resolvers, &artistResolver{objectID, &artist, g.store})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -130,6 +132,7 @@ func (g *Resolver) Artist(args artistArgs) ([]*artistResolver, error) {
 type artistResolver struct {
 	cid    string
 	artist *Artist
+	store  *meta.Store
 }
 
 func (a *artistResolver) Cid() string {
@@ -199,3 +202,9 @@ func (a *artistResolver) Annotation() *[]string {
 	}
 	return &a.artist.Annotation
 }
+
+// History is a GraphQL resolver function which loads the revision history of
+// the artist from the META store, most recent first.
+func (a *artistResolver) History() ([]*history.RevisionResolver, error) {
+	return history.Resolvers(a.store, a.cid)
+}
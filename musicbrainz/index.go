@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ipfs/go-cid"
 	"github.com/meta-network/go-meta"
+	"github.com/meta-network/go-meta/claimindex"
 )
 
 // Indexer is a META indexer which indexes a stream of META objects
@@ -51,7 +52,8 @@ func NewIndexer(indexDB *sql.DB, store *meta.Store) (*Indexer, error) {
 }
 
 // IndexArtists indexes a stream of META object links which are expected to
-// point at MusicBrainz Artists.
+// point at MusicBrainz Artists, or at claims about MusicBrainz Artists in
+// which case the signer of the claim is also indexed.
 func (i *Indexer) IndexArtists(ctx context.Context, stream chan *cid.Cid) error {
 	for {
 		select {
@@ -63,13 +65,22 @@ func (i *Indexer) IndexArtists(ctx context.Context, stream chan *cid.Cid) error
 			if err != nil {
 				return err
 			}
+			obj, claim, err := meta.ResolveClaim(i.store, obj)
+			if err != nil {
+				return err
+			}
 			artist := &Artist{}
 			if err := obj.Decode(artist); err != nil {
 				return err
 			}
-			if err := i.indexArtist(cid.String(), artist); err != nil {
+			if err := i.indexArtist(obj.Cid().String(), artist); err != nil {
 				return err
 			}
+			if claim != nil {
+				if err := claimindex.Index(i.indexDB, claim); err != nil {
+					return err
+				}
+			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
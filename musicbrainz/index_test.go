@@ -151,11 +151,46 @@ func TestIndexArtists(t *testing.T) {
 	}
 }
 
+// TestIndexClaims tests indexing a claim about a MusicBrainz artist records
+// the signer of the claim.
+func TestIndexClaims(t *testing.T) {
+	x, err := newTestIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer x.cleanup()
+
+	rows, err := x.db.Query(`SELECT object_id, signer FROM claim`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var claims [][2]string
+	for rows.Next() {
+		var objectID, signer string
+		if err := rows.Scan(&objectID, &signer); err != nil {
+			t.Fatal(err)
+		}
+		claims = append(claims, [2]string{objectID, signer})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]string{{x.claimed.String(), x.signer.String()}}
+	if !reflect.DeepEqual(claims, expected) {
+		t.Fatalf("unexpected claims:\nexpected: %v\nactual:   %v", expected, claims)
+	}
+}
+
 type testIndex struct {
 	db      *sql.DB
 	store   *meta.Store
 	artists []*Artist
 	tmpDir  string
+
+	// claimed is the artist which is indexed via a claim signed by signer
+	claimed *cid.Cid
+	signer  meta.Address
 }
 
 func (t *testIndex) cleanup() {
@@ -207,6 +242,22 @@ func newTestIndex() (x *testIndex, err error) {
 		cids[i] = obj.Cid()
 	}
 
+	// replace the first artist with a signed claim about it
+	key, err := meta.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	claim, err := meta.Sign(cids[0], key)
+	if err != nil {
+		return nil, err
+	}
+	if err := x.store.Put(claim); err != nil {
+		return nil, err
+	}
+	x.claimed = cids[0]
+	x.signer = key.Address()
+	cids[0] = claim.Cid()
+
 	// create a stream
 	stream := make(chan *cid.Cid, len(x.artists))
 	go func() {
@@ -19,7 +19,10 @@
 
 package musicbrainz
 
-import "github.com/meta-network/go-meta/migrate"
+import (
+	"github.com/meta-network/go-meta/claimindex"
+	"github.com/meta-network/go-meta/migrate"
+)
 
 // migrations is a set of database migrations to run on a SQLite3 database
 // to prepare it for indexing a META stream of MusicBrainz artists.
@@ -62,4 +65,8 @@ CREATE TABLE artist_isni (
 CREATE INDEX artist_isni_idx ON artist_isni (isni);
 `,
 	)
+
+	// migration 2 creates an index of the signers of claims about
+	// indexed artists so that they can be filtered by trusted sources
+	migrations.Add(2, claimindex.Migration)
 }
@@ -0,0 +1,191 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ipfs/go-cid"
+)
+
+// Set returns a new graph with the value at the given path set to value,
+// storing a new version of the object which contains the value and of every
+// ancestor object on the path, and leaving the original graph unchanged.
+//
+// Objects which are not on the path are shared by both graphs. If value is an
+// *Object then it is stored and linked to.
+func (g *Graph) Set(path []string, value interface{}) (*Graph, error) {
+	if obj, ok := value.(*Object); ok {
+		if err := g.store.Put(obj); err != nil {
+			return nil, err
+		}
+		value = obj.Cid()
+	}
+	return g.mutate(path, func(parent interface{}, key string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			parent[key] = value
+			return parent, nil
+		case []interface{}:
+			i, err := listIndex(parent, key)
+			if err != nil {
+				return nil, err
+			}
+			parent[i] = value
+			return parent, nil
+		default:
+			return nil, fmt.Errorf("meta: cannot set %q in value of type %T", key, parent)
+		}
+	})
+}
+
+// Delete returns a new graph with the value at the given path removed (see
+// Set for how the new graph is created).
+//
+// Deleting an element of a list removes it from the list, shifting any
+// subsequent elements down.
+func (g *Graph) Delete(path ...string) (*Graph, error) {
+	return g.mutate(path, func(parent interface{}, key string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := parent[key]; !ok {
+				return nil, ErrPathNotFound{path}
+			}
+			delete(parent, key)
+			return parent, nil
+		case []interface{}:
+			i, err := listIndex(parent, key)
+			if err != nil {
+				return nil, err
+			}
+			return append(parent[:i], parent[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("meta: cannot delete %q from value of type %T", key, parent)
+		}
+	})
+}
+
+// mutateFunc modifies the value with the given key in a map or list, returning
+// the modified map or list.
+type mutateFunc func(parent interface{}, key string) (interface{}, error)
+
+// mutate applies fn to the parent of the value at the given path and returns
+// a graph with the new root.
+func (g *Graph) mutate(path []string, fn mutateFunc) (*Graph, error) {
+	if len(path) == 0 || len(path) == 1 && path[0] == "" {
+		return nil, errors.New("meta: cannot modify the root of a graph")
+	}
+	m := &mutator{store: g.store, path: path, fn: fn}
+	root, err := m.object(g.root, path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGraph(g.store, root), nil
+}
+
+// mutator rewrites the objects on a path from the root of a graph.
+type mutator struct {
+	store *Store
+	path  []string
+	fn    mutateFunc
+}
+
+// object returns a new version of obj with the mutation applied at the given
+// path, storing the new object using the same CID prefix as the original.
+func (m *mutator) object(obj *Object, path []string) (*Object, error) {
+	properties, err := obj.Properties()
+	if err != nil {
+		return nil, err
+	}
+	v, err := m.value(properties, path)
+	if err != nil {
+		return nil, err
+	}
+	newObj, err := EncodeWithPrefix(v, obj.Cid().Prefix())
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.Put(newObj); err != nil {
+		return nil, err
+	}
+	return newObj, nil
+}
+
+// value returns a new version of v with the mutation applied at the given
+// path, following links into other objects.
+func (m *mutator) value(v interface{}, path []string) (interface{}, error) {
+	if id, ok := v.(*cid.Cid); ok {
+		obj, err := m.store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		newObj, err := m.object(obj, path)
+		if err != nil {
+			return nil, err
+		}
+		return newObj.Cid(), nil
+	}
+
+	if len(path) == 1 {
+		return m.fn(v, path[0])
+	}
+
+	key := path[0]
+	switch v := v.(type) {
+	case map[string]interface{}:
+		child, ok := v[key]
+		if !ok {
+			return nil, ErrPathNotFound{m.path}
+		}
+		newChild, err := m.value(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		v[key] = newChild
+		return v, nil
+	case []interface{}:
+		i, err := listIndex(v, key)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := m.value(v[i], path[1:])
+		if err != nil {
+			return nil, err
+		}
+		v[i] = newChild
+		return v, nil
+	default:
+		return nil, ErrPathNotFound{m.path}
+	}
+}
+
+// listIndex parses key as an index into the given list.
+func listIndex(list []interface{}, key string) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil {
+		return 0, fmt.Errorf("meta: invalid list index: %q", key)
+	}
+	if i < 0 || i >= len(list) {
+		return 0, fmt.Errorf("meta: list index out of range: %d", i)
+	}
+	return i, nil
+}
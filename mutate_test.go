@@ -0,0 +1,101 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestGraphSetDelete(t *testing.T) {
+	graph := testGraph(t)
+
+	get := func(g *Graph, path ...string) interface{} {
+		v, err := g.Get(path...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// set the name of the grandchild via child0
+	newGraph, err := graph.Set([]string{"children", "0", "children", "0", "name"}, "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newGraph.Root().Cid().Equals(graph.Root().Cid()) {
+		t.Fatal("expected Set to return a new root")
+	}
+	if v := get(newGraph, "children", "0", "children", "0", "name"); v != "renamed" {
+		t.Fatalf("expected new name %q, got %q", "renamed", v)
+	}
+
+	// check the original graph is unchanged
+	if v := get(graph, "children", "0", "children", "0", "name"); v != "grandchild" {
+		t.Fatalf("expected original name %q, got %q", "grandchild", v)
+	}
+
+	// check the sub-DAG which is not on the path is shared
+	oldChild1 := get(graph, "children", "1").(*cid.Cid)
+	newChild1 := get(newGraph, "children", "1").(*cid.Cid)
+	if !oldChild1.Equals(newChild1) {
+		t.Fatalf("expected child1 to be shared, got %s and %s", oldChild1, newChild1)
+	}
+
+	// set a value to an object
+	obj := MustEncode(map[string]string{"name": "pet"})
+	newGraph, err = newGraph.Set([]string{"pet"}, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := get(newGraph, "pet", "name"); v != "pet" {
+		t.Fatalf("expected pet name %q, got %q", "pet", v)
+	}
+
+	// delete the first child
+	newGraph, err = newGraph.Delete("children", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := get(newGraph, "children", "0", "name"); v != "child1" {
+		t.Fatalf("expected first child to be %q, got %q", "child1", v)
+	}
+	if _, err := newGraph.Get("children", "1"); err == nil {
+		t.Fatal("expected deleted child to be removed")
+	}
+
+	// delete a field
+	newGraph, err = newGraph.Delete("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGraph.Get("name"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+
+	// check missing paths return an error
+	if _, err := graph.Delete("missing"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+	if _, err := graph.Set([]string{"missing", "name"}, "x"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipld-format"
+	"github.com/lmars/go-ipld-cbor"
+)
+
+// Prove returns a proof that the value at the given path is reachable from
+// the root of the graph, consisting of the raw data of each object on the path
+// ordered from the root to the object which contains the value.
+//
+// The proof can be checked without access to the store using VerifyProof.
+func (g *Graph) Prove(path ...string) ([][]byte, error) {
+	if len(path) == 1 && path[0] == "" {
+		path = nil
+	}
+	proof := [][]byte{g.root.RawData()}
+	obj := g.root
+	for {
+		v, rest, err := obj.node.Resolve(path)
+		if err != nil {
+			if err == cbornode.ErrNoSuchLink {
+				err = ErrPathNotFound{path}
+			}
+			return nil, err
+		}
+		if len(rest) == 0 {
+			return proof, nil
+		}
+		link, ok := v.(*format.Link)
+		if !ok {
+			return nil, fmt.Errorf("meta: expected link object, got %T", v)
+		}
+		obj, err = g.store.Get(link.Cid)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, obj.RawData())
+		path = rest
+	}
+}
+
+// VerifyProof checks that the given proof, as returned by Graph.Prove, shows
+// the value at the given path to be reachable from the root CID, and returns
+// that value (the root object itself if the path is empty).
+//
+// Each block in the proof is verified against the CID which links to it, so
+// the returned value can be trusted as much as the root CID is.
+func VerifyProof(root *cid.Cid, path []string, proof [][]byte) (interface{}, error) {
+	if len(path) == 1 && path[0] == "" {
+		path = nil
+	}
+	id := root
+	for i, data := range proof {
+		obj, err := NewObject(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid proof block %d: %s", i, err)
+		}
+		if len(path) == 0 {
+			if len(proof) > 1 {
+				return nil, fmt.Errorf("meta: proof contains %d unused blocks", len(proof)-1)
+			}
+			return obj, nil
+		}
+		v, rest, err := obj.node.Resolve(path)
+		if err != nil {
+			if err == cbornode.ErrNoSuchLink {
+				err = ErrPathNotFound{path}
+			}
+			return nil, err
+		}
+		if len(rest) == 0 {
+			if i != len(proof)-1 {
+				return nil, fmt.Errorf("meta: proof contains %d unused blocks", len(proof)-1-i)
+			}
+			if l, ok := v.(*format.Link); ok {
+				v = l.Cid
+			}
+			return v, nil
+		}
+		link, ok := v.(*format.Link)
+		if !ok {
+			return nil, fmt.Errorf("meta: expected link object, got %T", v)
+		}
+		id = link.Cid
+		path = rest
+	}
+	return nil, errors.New("meta: proof is missing blocks")
+}
@@ -0,0 +1,88 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestGraphProve(t *testing.T) {
+	graph := testGraph(t)
+	root := graph.Root().Cid()
+
+	// check a value two links away from the root is proved by three blocks
+	path := []string{"children", "1", "children", "0", "name"}
+	proof, err := graph.Prove(path...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proof) != 3 {
+		t.Fatalf("expected proof to have 3 blocks, got %d", len(proof))
+	}
+	v, err := VerifyProof(root, path, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "grandchild" {
+		t.Fatalf("expected proved value %q, got %v", "grandchild", v)
+	}
+
+	// check a path ending at a link proves the link
+	proof, err = graph.Prove("children", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = VerifyProof(root, []string{"children", "0"}, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child0, err := graph.Get("children", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := v.(*cid.Cid); !ok || !id.Equals(child0.(*cid.Cid)) {
+		t.Fatalf("unexpected proved link: %v", v)
+	}
+
+	// check invalid proofs fail verification
+	proof, err = graph.Prove(path...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, invalid := range map[string][][]byte{
+		"tampered block": {proof[0], proof[1], MustEncode(map[string]string{"name": "forged"}).RawData()},
+		"missing block":  proof[:2],
+		"extra block":    append(proof[:3:3], proof[0]),
+	} {
+		if _, err := VerifyProof(root, path, invalid); err == nil {
+			t.Fatalf("expected proof with %s to fail verification", name)
+		}
+	}
+	if _, err := VerifyProof(root, []string{"children", "1", "name"}, proof); err == nil {
+		t.Fatal("expected proof of different path to fail verification")
+	}
+
+	// check proving a missing path fails
+	if _, err := graph.Prove("missing"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+}
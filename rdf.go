@@ -0,0 +1,365 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+// RDFFormat is an RDF serialization format.
+type RDFFormat string
+
+const (
+	// NQuads is the RDF N-Quads format (see
+	// https://www.w3.org/TR/n-quads/).
+	NQuads RDFFormat = "nquads"
+
+	// Turtle is the RDF Turtle format (see
+	// https://www.w3.org/TR/turtle/).
+	Turtle RDFFormat = "turtle"
+)
+
+// ErrUnknownRDFFormat is returned when trying to write RDF in an
+// unsupported format.
+type ErrUnknownRDFFormat struct {
+	Format RDFFormat
+}
+
+func (e ErrUnknownRDFFormat) Error() string {
+	return fmt.Sprintf("meta: unknown RDF format %q", e.Format)
+}
+
+const (
+	rdfType  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	rdfValue = "http://www.w3.org/1999/02/22-rdf-syntax-ns#value"
+	xsdNS    = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// RDFWriter writes META objects as RDF.
+//
+// Each object is expanded using its JSON-LD context and written with an
+// ipld:<cid> IRI as its subject. Property names and types which the context
+// does not expand to absolute IRIs are written as meta:<name> IRIs, nested
+// maps are written as blank nodes and links are written as the ipld:<cid>
+// IRI of the linked object.
+//
+// Output is buffered, so Flush must be called once all objects have been
+// written.
+type RDFWriter struct {
+	w        *bufio.Writer
+	format   RDFFormat
+	graph    string
+	expander *expander
+	blanks   int
+	started  bool
+	err      error
+}
+
+// NewRDFWriter returns an RDFWriter which writes objects to w in the given
+// format, loading JSON-LD contexts from the store. If graph is not nil,
+// N-Quads are placed in a graph named after it (the graph is ignored by
+// formats which only support triples).
+func NewRDFWriter(w io.Writer, store *Store, format RDFFormat, graph *cid.Cid) (*RDFWriter, error) {
+	switch format {
+	case NQuads, Turtle:
+	default:
+		return nil, ErrUnknownRDFFormat{format}
+	}
+	r := &RDFWriter{
+		w:        bufio.NewWriter(w),
+		format:   format,
+		expander: newExpander(store),
+	}
+	// contexts which are missing from the store (e.g. the XML schema
+	// contexts of an ERN) leave property names unexpanded
+	r.expander.ignoreMissing = true
+	if graph != nil {
+		r.graph = ipldIRI(graph)
+	}
+	return r, nil
+}
+
+// rdfTriple is an RDF triple with each term already serialized.
+type rdfTriple struct {
+	subject, predicate, object string
+}
+
+// WriteObject writes the given object as RDF.
+func (r *RDFWriter) WriteObject(obj *Object) error {
+	if r.err != nil {
+		return r.err
+	}
+	properties, err := r.expander.expand(obj)
+	if err != nil {
+		return err
+	}
+	var triples []rdfTriple
+	r.appendProperties(&triples, ipldIRI(obj.Cid()), properties)
+	switch r.format {
+	case NQuads:
+		r.writeNQuads(triples)
+	case Turtle:
+		r.writeTurtle(triples)
+	}
+	return r.err
+}
+
+// Flush writes any buffered output to the underlying writer.
+func (r *RDFWriter) Flush() error {
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.w.Flush()
+	return r.err
+}
+
+func (r *RDFWriter) printf(format string, args ...interface{}) {
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, format, args...)
+}
+
+func (r *RDFWriter) writeNQuads(triples []rdfTriple) {
+	for _, t := range triples {
+		if r.graph != "" {
+			r.printf("%s %s %s %s .\n", t.subject, t.predicate, t.object, r.graph)
+		} else {
+			r.printf("%s %s %s .\n", t.subject, t.predicate, t.object)
+		}
+	}
+}
+
+// writeTurtle writes triples as Turtle, grouping consecutive triples with
+// the same subject into a single statement.
+func (r *RDFWriter) writeTurtle(triples []rdfTriple) {
+	if !r.started {
+		r.printf("@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .\n")
+		r.printf("@prefix xsd: <%s> .\n", xsdNS)
+		r.started = true
+	}
+	for i, t := range triples {
+		if i == 0 || triples[i-1].subject != t.subject {
+			r.printf("\n%s\n", t.subject)
+		}
+		predicate := t.predicate
+		if predicate == "<"+rdfType+">" {
+			predicate = "a"
+		}
+		r.printf("    %s %s", predicate, t.object)
+		if i == len(triples)-1 || triples[i+1].subject != t.subject {
+			r.printf(" .\n")
+		} else {
+			r.printf(" ;\n")
+		}
+	}
+}
+
+// appendProperties appends triples for the given expanded properties,
+// sorted by key so that the output is deterministic.
+func (r *RDFWriter) appendProperties(triples *[]rdfTriple, subject string, properties map[string]interface{}) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// write the subject's own triples before those of any blank nodes
+	// so that Turtle statements are grouped by subject
+	var nested []func()
+	for _, key := range keys {
+		v := properties[key]
+		switch key {
+		case "@type":
+			for _, typ := range typeList(v) {
+				*triples = append(*triples, rdfTriple{subject, "<" + rdfType + ">", "<" + metaIRI(typ) + ">"})
+			}
+			continue
+		case "@value":
+			key = rdfValue
+		case "@id", "@context":
+			continue
+		default:
+			// skip other keywords such as the @namespace and
+			// @children properties of XML elements
+			if strings.HasPrefix(key, "@") {
+				continue
+			}
+		}
+		r.appendValues(triples, &nested, subject, "<"+metaIRI(key)+">", v)
+	}
+	for _, fn := range nested {
+		fn()
+	}
+}
+
+func (r *RDFWriter) appendValues(triples *[]rdfTriple, nested *[]func(), subject, predicate string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		for _, x := range v {
+			r.appendValues(triples, nested, subject, predicate, x)
+		}
+	case map[string]interface{}:
+		if literal, ok := valueLiteral(v); ok {
+			*triples = append(*triples, rdfTriple{subject, predicate, literal})
+			return
+		}
+		var node string
+		if id, ok := v["@id"].(string); ok {
+			node = "<" + metaIRI(id) + ">"
+		} else {
+			r.blanks++
+			node = fmt.Sprintf("_:b%d", r.blanks)
+		}
+		*triples = append(*triples, rdfTriple{subject, predicate, node})
+		*nested = append(*nested, func() { r.appendProperties(triples, node, v) })
+	case *cid.Cid:
+		*triples = append(*triples, rdfTriple{subject, predicate, ipldIRI(v)})
+	default:
+		*triples = append(*triples, rdfTriple{subject, predicate, rdfLiteral(v)})
+	}
+}
+
+// valueLiteral returns the literal represented by a JSON-LD value object
+// (i.e. a map with an @value and optionally a @type or @language), returning
+// false if the map has any other properties.
+func valueLiteral(v map[string]interface{}) (string, bool) {
+	value, ok := v["@value"]
+	if !ok {
+		return "", false
+	}
+	for key := range v {
+		switch key {
+		case "@value", "@type", "@language":
+		default:
+			return "", false
+		}
+	}
+	if typ, ok := v["@type"].(string); ok {
+		return rdfString(fmt.Sprint(value)) + "^^<" + metaIRI(typ) + ">", true
+	}
+	if lang, ok := v["@language"].(string); ok {
+		return rdfString(fmt.Sprint(value)) + "@" + lang, true
+	}
+	return rdfLiteral(value), true
+}
+
+// typeList returns the types in a @type value, which is either a string or
+// a list of strings.
+func typeList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		types := make([]string, 0, len(v))
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	default:
+		return nil
+	}
+}
+
+// ipldIRI returns the serialized ipld:<cid> IRI of the given CID.
+func ipldIRI(id *cid.Cid) string {
+	return "<ipld:" + id.String() + ">"
+}
+
+// metaIRI returns the IRI for a META property name or type, leaving names
+// which are already absolute or prefixed unchanged.
+func metaIRI(name string) string {
+	if strings.Contains(name, ":") {
+		return rdfIRIEscaper.Replace(name)
+	}
+	return "meta:" + rdfIRIEscaper.Replace(name)
+}
+
+// rdfIRIEscaper percent-encodes characters which are not allowed in IRIs.
+var rdfIRIEscaper = strings.NewReplacer(
+	" ", "%20",
+	"<", "%3C",
+	">", "%3E",
+	`"`, "%22",
+	"{", "%7B",
+	"}", "%7D",
+	"|", "%7C",
+	"^", "%5E",
+	"`", "%60",
+	`\`, "%5C",
+)
+
+func rdfLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return rdfString(v)
+	case bool:
+		return fmt.Sprintf(`"%t"^^<%sboolean>`, v, xsdNS)
+	case float64:
+		return fmt.Sprintf(`"%s"^^<%sdouble>`, strconv.FormatFloat(v, 'g', -1, 64), xsdNS)
+	case uint64, int64, int:
+		return fmt.Sprintf(`"%d"^^<%sinteger>`, v, xsdNS)
+	default:
+		return rdfString(fmt.Sprint(v))
+	}
+}
+
+var rdfStringEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+func rdfString(s string) string {
+	return `"` + rdfStringEscaper.Replace(s) + `"`
+}
+
+// ExportRDF writes the graph of objects reachable from the given root as RDF
+// to w, streaming objects as they are visited rather than loading the whole
+// graph into memory. N-Quads are placed in a graph named after the root.
+func ExportRDF(ctx context.Context, store *Store, root *cid.Cid, w io.Writer, format RDFFormat) error {
+	r, err := NewRDFWriter(w, store, format, root)
+	if err != nil {
+		return err
+	}
+	obj, err := store.Get(root)
+	if err != nil {
+		return err
+	}
+	err = NewGraph(store, obj).Walk(ctx, func(_ []string, obj *Object, _ int) error {
+		return r.WriteObject(obj)
+	}, &WalkOptions{IgnoreMissing: true})
+	if err != nil {
+		return err
+	}
+	return r.Flush()
+}
@@ -0,0 +1,110 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestExportRDF(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *Object {
+		obj := MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	schema := put(map[string]interface{}{
+		"@context": map[string]interface{}{
+			"schema": "http://schema.org/",
+			"Person": "schema:Person",
+			"name":   "schema:name",
+		},
+	})
+	child := put(map[string]interface{}{
+		"@context": schema.Cid(),
+		"@type":    "Person",
+		"name":     map[string]interface{}{"@value": "child", "@language": "en"},
+	})
+	parent := put(map[string]interface{}{
+		"@context": schema.Cid(),
+		"@type":    "Person",
+		"name":     "parent",
+		"age":      42,
+		"children": []*cid.Cid{child.Cid()},
+		"id":       map[string]interface{}{"@value": "P1", "Namespace": "DPID"},
+	})
+
+	export := func(format RDFFormat) string {
+		var buf bytes.Buffer
+		if err := ExportRDF(context.Background(), store, parent.Cid(), &buf, format); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	p := "<ipld:" + parent.Cid().String() + ">"
+	c := "<ipld:" + child.Cid().String() + ">"
+	expected := p + ` <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Person> ` + p + ` .
+` + p + ` <meta:age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> ` + p + ` .
+` + p + ` <meta:children> ` + c + ` ` + p + ` .
+` + p + ` <http://schema.org/name> "parent" ` + p + ` .
+` + p + ` <meta:id> _:b1 ` + p + ` .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#value> "P1" ` + p + ` .
+_:b1 <meta:Namespace> "DPID" ` + p + ` .
+` + c + ` <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Person> ` + p + ` .
+` + c + ` <http://schema.org/name> "child"@en ` + p + ` .
+`
+	if out := export(NQuads); out != expected {
+		t.Fatalf("unexpected N-Quads output:\nexpected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	expected = `@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+` + p + `
+    a <http://schema.org/Person> ;
+    <meta:age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> ;
+    <meta:children> ` + c + ` ;
+    <http://schema.org/name> "parent" ;
+    <meta:id> _:b1 .
+
+_:b1
+    <http://www.w3.org/1999/02/22-rdf-syntax-ns#value> "P1" ;
+    <meta:Namespace> "DPID" .
+
+` + c + `
+    a <http://schema.org/Person> ;
+    <http://schema.org/name> "child"@en .
+`
+	if out := export(Turtle); out != expected {
+		t.Fatalf("unexpected Turtle output:\nexpected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	if err := ExportRDF(context.Background(), store, parent.Cid(), &bytes.Buffer{}, "rdfxml"); err == nil {
+		t.Fatal("expected error exporting unknown RDF format")
+	}
+}
@@ -0,0 +1,309 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+var (
+	// refsPrefix is the datastore namespace which refs are stored under.
+	refsPrefix = datastore.NewKey("refs")
+
+	// refLogPrefix is the datastore namespace which ref logs are stored
+	// under, with each entry of the log for a ref stored as a separate
+	// key below the ref's name (e.g. /reflog/artists/latest/<time>).
+	refLogPrefix = datastore.NewKey("reflog")
+)
+
+// Refs maps names (e.g. "musicbrainz/artists/latest") to the CIDs of objects
+// in a store, keeping a log of every update to each name.
+//
+// Updates are atomic for all Refs values returned from the same Store, and
+// an update writes the ref and its log entry together. If the store's
+// datastore is a TxnDatastore (e.g. the sqlite datastore) then updates are
+// also atomic across processes sharing the datastore, otherwise concurrent
+// updates from different processes can both pass the check made by Update.
+type Refs struct {
+	store *Store
+}
+
+// Refs returns the refs which are kept alongside the objects in the store.
+func (s *Store) Refs() *Refs {
+	return &Refs{store: s}
+}
+
+// RefLogEntry is an entry in the log of updates to a ref.
+type RefLogEntry struct {
+	// Old is the CID the ref pointed at before the update, or nil if the
+	// ref was created.
+	Old *cid.Cid `json:"old,omitempty"`
+
+	// New is the CID the ref pointed at after the update.
+	New *cid.Cid `json:"new"`
+
+	// Time is the time the update happened.
+	Time time.Time `json:"time"`
+
+	// Message describes the reason for the update.
+	Message string `json:"message,omitempty"`
+}
+
+// Get returns the CID which the ref with the given name points at.
+func (r *Refs) Get(name string) (*cid.Cid, error) {
+	key, err := refKey(refsPrefix, name)
+	if err != nil {
+		return nil, err
+	}
+	return getRef(r.store.store, name, key)
+}
+
+func getRef(ds datastore.Datastore, name string, key datastore.Key) (*cid.Cid, error) {
+	data, err := ds.Get(key)
+	if err == datastore.ErrNotFound {
+		return nil, ErrRefNotFound{name}
+	} else if err != nil {
+		return nil, err
+	}
+	v, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("meta: unexpected value type for ref %s: %T", name, data)
+	}
+	return cid.Cast(v)
+}
+
+// Set points the ref with the given name at the given CID regardless of its
+// current value, recording the update in the ref log with the given message.
+func (r *Refs) Set(name string, id *cid.Cid, message string) error {
+	return r.update(name, nil, false, id, message)
+}
+
+// Update atomically points the ref with the given name at the CID new only if
+// it currently points at old (or does not exist if old is nil), returning an
+// ErrRefConflict otherwise.
+func (r *Refs) Update(name string, old, new *cid.Cid, message string) error {
+	return r.update(name, old, true, new, message)
+}
+
+func (r *Refs) update(name string, old *cid.Cid, check bool, new *cid.Cid, message string) error {
+	key, err := refKey(refsPrefix, name)
+	if err != nil {
+		return err
+	}
+	r.store.gcMtx.RLock()
+	defer r.store.gcMtx.RUnlock()
+	if has, err := r.store.Has(new); err != nil {
+		return err
+	} else if !has {
+		return fmt.Errorf("meta: cannot set ref %s to %s, object not found", name, new)
+	}
+
+	logKey, err := refKey(refLogPrefix, name)
+	if err != nil {
+		return err
+	}
+
+	r.store.refsMtx.Lock()
+	defer r.store.refsMtx.Unlock()
+
+	return r.store.txn(func(ds datastore.Datastore) error {
+		current, err := getRef(ds, name, key)
+		if IsRefNotFound(err) {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+		if check && !cidEqual(current, old) {
+			return ErrRefConflict{Name: name, Expected: old, Actual: current}
+		}
+
+		entry := &RefLogEntry{
+			Old:     current,
+			New:     new,
+			Time:    time.Now().UTC(),
+			Message: message,
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		entryKey, err := refLogEntryKey(ds, logKey, entry.Time)
+		if err != nil {
+			return err
+		}
+		if err := ds.Put(entryKey, data); err != nil {
+			return err
+		}
+		return ds.Put(key, new.Bytes())
+	})
+}
+
+// refLogEntryKey returns an unused key below logKey for a log entry created
+// at the given time, with keys sorting in the order the entries were created.
+func refLogEntryKey(ds datastore.Datastore, logKey datastore.Key, t time.Time) (datastore.Key, error) {
+	for n := t.UnixNano(); ; n++ {
+		key := logKey.ChildString(fmt.Sprintf("%020d", n))
+		if has, err := ds.Has(key); err != nil {
+			return datastore.Key{}, err
+		} else if !has {
+			return key, nil
+		}
+	}
+}
+
+// List returns the CIDs of all refs keyed by name.
+func (r *Refs) List() (map[string]*cid.Cid, error) {
+	results, err := r.store.store.Query(query.Query{Prefix: refsPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]*cid.Cid, len(entries))
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if !refsPrefix.IsAncestorOf(key) {
+			continue
+		}
+		name := strings.TrimPrefix(key.String(), refsPrefix.String()+"/")
+		id, err := getRef(r.store.store, name, key)
+		if err != nil {
+			return nil, err
+		}
+		refs[name] = id
+	}
+	return refs, nil
+}
+
+// Log returns the log of updates to the ref with the given name, most recent
+// first.
+func (r *Refs) Log(name string) ([]*RefLogEntry, error) {
+	logKey, err := refKey(refLogPrefix, name)
+	if err != nil {
+		return nil, err
+	}
+	results, err := r.store.store.Query(query.Query{Prefix: logKey.String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	// the prefix also matches the logs of other refs which either have
+	// this name as a prefix or are nested below it, so only keep direct
+	// children of the log key
+	var keys []string
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if key.Parent().Equal(logKey) {
+			keys = append(keys, key.String())
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+
+	log := make([]*RefLogEntry, len(keys))
+	for i, key := range keys {
+		data, err := r.store.store.Get(datastore.NewKey(key))
+		if err != nil {
+			return nil, err
+		}
+		v, ok := data.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("meta: unexpected value type for ref log %s: %T", name, data)
+		}
+		log[i] = &RefLogEntry{}
+		if err := json.Unmarshal(v, log[i]); err != nil {
+			return nil, err
+		}
+	}
+	return log, nil
+}
+
+// logCids returns the old and new CIDs of every entry in the logs of all
+// refs.
+func (r *Refs) logCids() ([]*cid.Cid, error) {
+	results, err := r.store.store.Query(query.Query{Prefix: refLogPrefix.String()})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	var ids []*cid.Cid
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if !refLogPrefix.IsAncestorOf(key) {
+			continue
+		}
+		value := entry.Value
+		if _, ok := value.([]byte); !ok {
+			// some datastores do not return values from
+			// queries so load it explicitly
+			value, err = r.store.store.Get(key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		data, ok := value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("meta: unexpected value type for ref log %s: %T", key, value)
+		}
+		var log RefLogEntry
+		if err := json.Unmarshal(data, &log); err != nil {
+			return nil, err
+		}
+		if log.Old != nil {
+			ids = append(ids, log.Old)
+		}
+		ids = append(ids, log.New)
+	}
+	return ids, nil
+}
+
+// refKey returns the datastore key under the given prefix for the ref with
+// the given name, which must be a clean slash separated path.
+func refKey(prefix datastore.Key, name string) (datastore.Key, error) {
+	key := prefix.Child(datastore.NewKey(name))
+	if name == "" || key.String() != prefix.String()+"/"+name {
+		return datastore.Key{}, fmt.Errorf("meta: invalid ref name: %q", name)
+	}
+	return key, nil
+}
+
+// cidEqual returns whether two possibly nil CIDs are equal.
+func cidEqual(a, b *cid.Cid) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equals(b)
+}
@@ -0,0 +1,126 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestRefs(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	v1 := MustEncode(map[string]string{"name": "v1"})
+	v2 := MustEncode(map[string]string{"name": "v2"})
+	for _, obj := range []*Object{v1, v2} {
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refs := store.Refs()
+	name := "artists/latest"
+
+	// check getting a missing ref fails
+	if _, err := refs.Get(name); !IsRefNotFound(err) {
+		t.Fatalf("expected ref not found error, got %v", err)
+	}
+
+	// create the ref and check it can be read
+	if err := refs.Update(name, nil, v1.Cid(), "create"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := refs.Get(name); err != nil {
+		t.Fatal(err)
+	} else if !id.Equals(v1.Cid()) {
+		t.Fatalf("expected ref to point at %s, got %s", v1.Cid(), id)
+	}
+
+	// check an update with the wrong old value fails
+	if err := refs.Update(name, nil, v2.Cid(), "conflict"); err == nil {
+		t.Fatal("expected conflicting update to fail")
+	} else if _, ok := err.(ErrRefConflict); !ok {
+		t.Fatalf("expected ref conflict error, got %T", err)
+	}
+
+	// check an update with the correct old value succeeds
+	if err := refs.Update(name, v1.Cid(), v2.Cid(), "update"); err != nil {
+		t.Fatal(err)
+	}
+
+	// check pointing a ref at a missing object fails
+	missing := MustEncode(map[string]string{"name": "missing"})
+	if err := refs.Set(name, missing.Cid(), "missing"); err == nil {
+		t.Fatal("expected setting ref to missing object to fail")
+	}
+
+	// check invalid names are rejected
+	for _, invalid := range []string{"", "/latest", "artists/", "artists//latest", "artists/../latest"} {
+		if err := refs.Set(invalid, v1.Cid(), ""); err == nil {
+			t.Fatalf("expected invalid name %q to be rejected", invalid)
+		}
+	}
+
+	// check the ref is listed
+	if err := refs.Set("other", v1.Cid(), ""); err != nil {
+		t.Fatal(err)
+	}
+	list, err := refs.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[name].Equals(v2.Cid()) || !list["other"].Equals(v1.Cid()) {
+		t.Fatalf("unexpected refs: %v", list)
+	}
+
+	// check the log
+	log, err := refs.Log(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(log))
+	}
+	if log[0].Message != "update" || !log[0].Old.Equals(v1.Cid()) || !log[0].New.Equals(v2.Cid()) {
+		t.Fatalf("unexpected log entry: %+v", log[0])
+	}
+	if log[1].Message != "create" || log[1].Old != nil || !log[1].New.Equals(v1.Cid()) {
+		t.Fatalf("unexpected log entry: %+v", log[1])
+	}
+
+	// check the logs of refs nested below or prefixed by another ref's
+	// name are kept separate
+	for _, other := range []string{"artists", "artists/latest/v2", "artists/latestx"} {
+		if err := refs.Set(other, v1.Cid(), other); err != nil {
+			t.Fatal(err)
+		}
+		log, err := refs.Log(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(log) != 1 || log[0].Message != other {
+			t.Fatalf("unexpected log for %s: %+v", other, log)
+		}
+	}
+	if log, err := refs.Log(name); err != nil {
+		t.Fatal(err)
+	} else if len(log) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(log))
+	}
+}
@@ -0,0 +1,292 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+)
+
+// DefaultResolverConcurrency is the default maximum number of objects a
+// Resolver fetches from the store concurrently.
+const DefaultResolverConcurrency = 16
+
+// Resolver resolves paths and links in a graph for the duration of a single
+// traversal, fetching objects from the store concurrently and caching them
+// so that each object is only fetched once.
+//
+// Whenever a path is resolved through a list or map of links (e.g. the
+// SoundRecordings of a large ERN ResourceList or the records of a CWR
+// transaction), the sibling links are prefetched in the background so that
+// resolving the paths to those siblings does not wait on the store.
+//
+// Cancelling the context passed to NewResolver stops any pending prefetches
+// and causes calls which are waiting on the store to return the context's
+// error.
+type Resolver struct {
+	ctx   context.Context
+	store *Store
+
+	// sem limits the number of concurrent fetches.
+	sem chan struct{}
+
+	mtx   sync.Mutex
+	calls map[string]*resolverCall
+}
+
+// resolverCall is a fetch of a single object which is either in progress
+// or complete (in which case done is closed).
+type resolverCall struct {
+	done chan struct{}
+	obj  *Object
+	err  error
+}
+
+// NewResolver returns a Resolver which fetches objects from the store, with
+// at most concurrency fetches in progress at once (defaulting to
+// DefaultResolverConcurrency if concurrency is not positive).
+func NewResolver(ctx context.Context, store *Store, concurrency int) *Resolver {
+	if concurrency <= 0 {
+		concurrency = DefaultResolverConcurrency
+	}
+	return &Resolver{
+		ctx:   ctx,
+		store: store,
+		sem:   make(chan struct{}, concurrency),
+		calls: make(map[string]*resolverCall),
+	}
+}
+
+// Get returns the object with the given CID, either from the cache, by
+// waiting for an in progress fetch or by fetching it from the store.
+func (r *Resolver) Get(id *cid.Cid) (*Object, error) {
+	if err := r.ctx.Err(); err != nil {
+		return nil, err
+	}
+	call, started := r.call(id)
+	if started {
+		select {
+		case r.sem <- struct{}{}:
+		case <-r.ctx.Done():
+			r.finish(id, call, nil, r.ctx.Err())
+			return nil, r.ctx.Err()
+		}
+		r.fetch(id, call)
+	}
+	select {
+	case <-call.done:
+		return call.obj, call.err
+	case <-r.ctx.Done():
+		return nil, r.ctx.Err()
+	}
+}
+
+// GetAll fetches the objects with the given CIDs concurrently, returning them
+// in the same order as the CIDs.
+func (r *Resolver) GetAll(ids []*cid.Cid) ([]*Object, error) {
+	r.Prefetch(ids...)
+	objs := make([]*Object, len(ids))
+	for i, id := range ids {
+		obj, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		objs[i] = obj
+	}
+	return objs, nil
+}
+
+// Prefetch starts fetching the objects with the given CIDs in the background
+// so that later calls to Get do not need to wait on the store.
+func (r *Resolver) Prefetch(ids ...*cid.Cid) {
+	if len(ids) == 0 {
+		return
+	}
+	go func() {
+		for _, id := range ids {
+			// wait for a free slot before starting the call so that
+			// a concurrent Get fetches the object itself rather
+			// than waiting behind the prefetch queue
+			select {
+			case r.sem <- struct{}{}:
+			case <-r.ctx.Done():
+				return
+			}
+			call, started := r.call(id)
+			if !started {
+				<-r.sem
+				continue
+			}
+			go r.fetch(id, call)
+		}
+	}()
+}
+
+// PrefetchLinks prefetches every object linked from v, which is typically a
+// value returned by Resolve, without following the links any further.
+func (r *Resolver) PrefetchLinks(v interface{}) {
+	var ids []*cid.Cid
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case *cid.Cid:
+			ids = append(ids, v)
+		case map[string]interface{}:
+			for _, x := range v {
+				collect(x)
+			}
+		case []interface{}:
+			for _, x := range v {
+				collect(x)
+			}
+		}
+	}
+	collect(v)
+	r.Prefetch(ids...)
+}
+
+// Graph returns a Graph with the given root which loads linked objects
+// using the resolver, so that Graph methods such as Select and DecodePath
+// share its cache.
+func (r *Resolver) Graph(root *Object) *Graph {
+	return &Graph{store: r.store, root: root, get: r.Get}
+}
+
+// Resolve returns the value at the given path from the root object, like
+// Graph.Get, fetching linked objects using the resolver and prefetching the
+// siblings of each link it follows.
+//
+// Resolve decodes the root object on each call, so to resolve many paths
+// below a common prefix (e.g. each transaction of a CWR), resolve the
+// prefix once and use ResolveValue for the rest of each path.
+func (r *Resolver) Resolve(root *Object, path ...string) (interface{}, error) {
+	if len(path) == 0 || len(path) == 1 && path[0] == "" {
+		return root, nil
+	}
+	v, err := root.Properties()
+	if err != nil {
+		return nil, err
+	}
+	return r.ResolveValue(v, path...)
+}
+
+// ResolveValue returns the value at the given path relative to v, which is
+// typically a value returned by Resolve, following links in the same way as
+// Resolve.
+func (r *Resolver) ResolveValue(v interface{}, path ...string) (interface{}, error) {
+	cur := v
+	for i, key := range path {
+		// load the object if the current value is a link
+		if id, ok := cur.(*cid.Cid); ok {
+			obj, err := r.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			if cur, err = obj.Properties(); err != nil {
+				return nil, err
+			}
+		}
+
+		var next interface{}
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			x, ok := c[key]
+			if !ok {
+				return nil, ErrPathNotFound{path}
+			}
+			next = x
+		case []interface{}:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 || n >= len(c) {
+				return nil, ErrPathNotFound{path}
+			}
+			next = c[n]
+		default:
+			return nil, fmt.Errorf("meta: cannot resolve %q in value of type %T at path %v", key, cur, path[:i])
+		}
+
+		// prefetch the siblings of a link which is about to be
+		// followed
+		if _, ok := next.(*cid.Cid); ok && i < len(path)-1 {
+			r.prefetchSiblings(cur)
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// prefetchSiblings prefetches the links which are direct children of the
+// given map or list.
+func (r *Resolver) prefetchSiblings(v interface{}) {
+	var ids []*cid.Cid
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, x := range v {
+			if id, ok := x.(*cid.Cid); ok {
+				ids = append(ids, id)
+			}
+		}
+	case []interface{}:
+		for _, x := range v {
+			if id, ok := x.(*cid.Cid); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	r.Prefetch(ids...)
+}
+
+// call returns the call for the given CID, creating it if it does not exist
+// in which case started is true and the caller must fetch the object.
+func (r *Resolver) call(id *cid.Cid) (call *resolverCall, started bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	key := id.KeyString()
+	if call, ok := r.calls[key]; ok {
+		return call, false
+	}
+	call = &resolverCall{done: make(chan struct{})}
+	r.calls[key] = call
+	return call, true
+}
+
+// fetch fetches an object from the store, releasing the semaphore slot
+// acquired by the caller once done.
+func (r *Resolver) fetch(id *cid.Cid, call *resolverCall) {
+	defer func() { <-r.sem }()
+	obj, err := r.store.Get(id)
+	r.finish(id, call, obj, err)
+}
+
+// finish completes a call, forgetting it if it failed because the context
+// was cancelled so that it is not cached.
+func (r *Resolver) finish(id *cid.Cid, call *resolverCall, obj *Object, err error) {
+	if err != nil && err == r.ctx.Err() {
+		r.mtx.Lock()
+		delete(r.calls, id.KeyString())
+		r.mtx.Unlock()
+	}
+	call.obj, call.err = obj, err
+	close(call.done)
+}
@@ -0,0 +1,174 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// slowDatastore is a datastore which takes a while to get values and counts
+// how many gets it serves, and how many it serves concurrently.
+type slowDatastore struct {
+	*datastore.MapDatastore
+
+	mtx           sync.Mutex
+	gets          int
+	active        int
+	maxActive     int
+	latency       time.Duration
+	keysRequested map[datastore.Key]int
+}
+
+func (s *slowDatastore) Get(key datastore.Key) (interface{}, error) {
+	s.mtx.Lock()
+	s.gets++
+	s.keysRequested[key]++
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	s.mtx.Unlock()
+	time.Sleep(s.latency)
+	s.mtx.Lock()
+	s.active--
+	s.mtx.Unlock()
+	return s.MapDatastore.Get(key)
+}
+
+func TestResolver(t *testing.T) {
+	ds := &slowDatastore{
+		MapDatastore:  datastore.NewMapDatastore(),
+		latency:       10 * time.Millisecond,
+		keysRequested: make(map[datastore.Key]int),
+	}
+	store := NewStore(ds)
+	const n = 40
+	items := make([]*cid.Cid, n)
+	for i := range items {
+		obj := MustEncode(map[string]interface{}{"name": "item" + strconv.Itoa(i)})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		items[i] = obj.Cid()
+	}
+	list := MustEncode(map[string]interface{}{"items": items})
+	if err := store.Put(list); err != nil {
+		t.Fatal(err)
+	}
+	root := MustEncode(map[string]interface{}{"list": list.Cid()})
+
+	const concurrency = 4
+	r := NewResolver(context.Background(), store, concurrency)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		v, err := r.Resolve(root, "list", "items", strconv.Itoa(i), "name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "item" + strconv.Itoa(i); v != expected {
+			t.Fatalf("expected %q, got %v", expected, v)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// check each object was fetched once, with fetches happening
+	// concurrently but within the limit
+	if ds.gets != n+1 {
+		t.Fatalf("expected %d gets, got %d", n+1, ds.gets)
+	}
+	for key, count := range ds.keysRequested {
+		if count != 1 {
+			t.Fatalf("expected %s to be fetched once, got %d", key, count)
+		}
+	}
+	if ds.maxActive < 2 || ds.maxActive > concurrency {
+		t.Fatalf("expected between 2 and %d concurrent gets, got %d", concurrency, ds.maxActive)
+	}
+	if sequential := time.Duration(n+1) * ds.latency; elapsed >= sequential {
+		t.Fatalf("expected resolving to take less than %s, took %s", sequential, elapsed)
+	}
+
+	// check resolving links and missing paths
+	v, err := r.Resolve(root, "list", "items", "3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := v.(*cid.Cid); !ok || !id.Equals(items[3]) {
+		t.Fatalf("expected link to %s, got %v", items[3], v)
+	}
+	if _, err := r.Resolve(root, "list", "missing"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+	if _, err := r.Resolve(root, "list", "items", "100"); !IsPathNotFound(err) {
+		t.Fatalf("expected path not found error, got %v", err)
+	}
+
+	// check ResolveValue and a resolver Graph load objects from the
+	// resolver's cache rather than the store
+	gets := ds.gets
+	v, err = r.Resolve(root, "list", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = r.ResolveValue(v, "5", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "item5" {
+		t.Fatalf("expected %q, got %v", "item5", v)
+	}
+	var item struct {
+		Name string `meta:"name"`
+	}
+	if err := r.Graph(root).DecodePath(&item, "list", "items", "7"); err != nil {
+		t.Fatal(err)
+	}
+	if item.Name != "item7" {
+		t.Fatalf("expected %q, got %q", "item7", item.Name)
+	}
+	if ds.gets != gets {
+		t.Fatalf("expected no more gets from the store, got %d", ds.gets-gets)
+	}
+
+	// check GetAll returns objects in order using a new resolver
+	objs, err := NewResolver(context.Background(), store, 0).GetAll(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range objs {
+		if !obj.Cid().Equals(items[i]) {
+			t.Fatalf("expected object %d to be %s, got %s", i, items[i], obj.Cid())
+		}
+	}
+
+	// check a cancelled resolver does not fetch objects
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewResolver(ctx, store, 0).Get(items[0]); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
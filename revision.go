@@ -0,0 +1,153 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+)
+
+const (
+	// previousKey is the property which links a revision of an object
+	// to the previous revision.
+	previousKey = "@previous"
+
+	// revisionKey is the property which contains the revision metadata
+	// of a revision of an object.
+	revisionKey = "@revision"
+)
+
+// Revision is metadata describing why and by whom an object was revised.
+type Revision struct {
+	Author string
+	Time   time.Time
+	Reason string
+}
+
+// Revise stores and returns a new revision of prev with the given property
+// changes applied (a nil value removes the property), which links to prev
+// using the @previous property and contains the given revision metadata in
+// the @revision property.
+//
+// If rev is nil then an empty Revision is used, and if rev.Time is zero then
+// the current time is used.
+func Revise(store *Store, prev *Object, changes map[string]interface{}, rev *Revision) (*Object, error) {
+	if rev == nil {
+		rev = &Revision{}
+	}
+	properties, err := prev.Properties()
+	if err != nil {
+		return nil, err
+	}
+	for key, v := range changes {
+		if key == previousKey || key == revisionKey {
+			return nil, fmt.Errorf("meta: cannot change the %s property of a revision", key)
+		}
+		if v == nil {
+			delete(properties, key)
+		} else {
+			properties[key] = v
+		}
+	}
+	t := rev.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	properties[previousKey] = prev.Cid()
+	properties[revisionKey] = map[string]interface{}{
+		"author": rev.Author,
+		"time":   t.UTC().Format(time.RFC3339),
+		"reason": rev.Reason,
+	}
+	obj, err := EncodeWithPrefix(properties, prev.Cid().Prefix())
+	if err != nil {
+		return nil, err
+	}
+	if err := store.Put(obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Previous returns the CID of the previous revision of the object, or nil if
+// it is not a revision of another object.
+func (o *Object) Previous() (*cid.Cid, error) {
+	properties, err := o.Properties()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := properties[previousKey]
+	if !ok {
+		return nil, nil
+	}
+	id, ok := v.(*cid.Cid)
+	if !ok {
+		return nil, fmt.Errorf("meta: expected %s to be a link, got %T", previousKey, v)
+	}
+	return id, nil
+}
+
+// Revision returns the revision metadata of the object, or nil if it is not
+// a revision of another object.
+func (o *Object) Revision() (*Revision, error) {
+	properties, err := o.Properties()
+	if err != nil {
+		return nil, err
+	}
+	v, ok := properties[revisionKey]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("meta: expected %s to be a map, got %T", revisionKey, v)
+	}
+	rev := &Revision{}
+	rev.Author, _ = m["author"].(string)
+	rev.Reason, _ = m["reason"].(string)
+	if s, ok := m["time"].(string); ok {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("meta: invalid revision time %q: %s", s, err)
+		}
+		rev.Time = t
+	}
+	return rev, nil
+}
+
+// History returns the object with the given CID followed by each of its
+// previous revisions, most recent first.
+func History(store *Store, id *cid.Cid) ([]*Object, error) {
+	var history []*Object
+	for id != nil {
+		obj, err := store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, obj)
+		id, err = obj.Previous()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
@@ -0,0 +1,106 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+)
+
+func TestRevise(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	v1 := MustEncode(map[string]string{"name": "Jane", "country": "GB"})
+	if err := store.Put(v1); err != nil {
+		t.Fatal(err)
+	}
+
+	// revise the object twice
+	t2 := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	v2, err := Revise(store, v1, map[string]interface{}{"name": "Janet"}, &Revision{
+		Author: "alice",
+		Time:   t2,
+		Reason: "typo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3, err := Revise(store, v2, map[string]interface{}{"country": nil}, &Revision{
+		Author: "bob",
+		Reason: "unknown country",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check the changes were applied
+	if name, err := v3.GetString("name"); err != nil {
+		t.Fatal(err)
+	} else if name != "Janet" {
+		t.Fatalf("expected name %q, got %q", "Janet", name)
+	}
+	properties, err := v3.Properties()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := properties["country"]; ok {
+		t.Fatal("expected country to be removed")
+	}
+
+	// check the revision metadata
+	rev, err := v2.Revision()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Author != "alice" || rev.Reason != "typo" || !rev.Time.Equal(t2) {
+		t.Fatalf("unexpected revision: %+v", rev)
+	}
+	if rev, err := v1.Revision(); err != nil {
+		t.Fatal(err)
+	} else if rev != nil {
+		t.Fatalf("expected original object to have no revision, got %+v", rev)
+	}
+
+	// check the history
+	history, err := History(store, v3.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*Object{v3, v2, v1}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d revisions, got %d", len(expected), len(history))
+	}
+	for i, obj := range history {
+		if !obj.Cid().Equals(expected[i].Cid()) {
+			t.Fatalf("expected revision %d to be %s, got %s", i, expected[i].Cid(), obj.Cid())
+		}
+	}
+	// check a nil revision is treated as an empty one
+	v4, err := Revise(store, v3, map[string]interface{}{"name": "Jan"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev, err := v4.Revision(); err != nil {
+		t.Fatal(err)
+	} else if rev == nil || rev.Author != "" || rev.Reason != "" || rev.Time.IsZero() {
+		t.Fatalf("unexpected revision: %+v", rev)
+	}
+}
@@ -0,0 +1,94 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// This file adapts the secp256k1 implementation in
+// github.com/btcsuite/btcd/btcec to produce and recover public keys from 65
+// byte [R || S || V] signatures in the same way as Ethereum.
+
+// signatureLength is the length of a [R || S || V] signature.
+const signatureLength = 65
+
+// compactSigMagicOffset is added to the recovery ID V of a compact signature
+// created with an uncompressed public key.
+const compactSigMagicOffset = 27
+
+// secp256k1HalfN is half the order of the secp256k1 curve, which S values of
+// signatures must not exceed.
+var secp256k1HalfN = new(big.Int).Rsh(btcec.S256().N, 1)
+
+// ecPublicKey returns the uncompressed 64 byte [X || Y] encoding of a public
+// key.
+func ecPublicKey(pub *btcec.PublicKey) []byte {
+	return pub.SerializeUncompressed()[1:]
+}
+
+// ecSign signs the given 32 byte hash with the private key, using a
+// deterministic nonce (see RFC 6979) and a low S value.
+func ecSign(key *btcec.PrivateKey, hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("meta: signature hash must be 32 bytes")
+	}
+
+	// convert the [V || R || S] compact signature to [R || S || V]
+	compact, err := btcec.SignCompact(btcec.S256(), key, hash, false)
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, signatureLength)
+	copy(sig, compact[1:])
+	sig[64] = compact[0] - compactSigMagicOffset
+	return sig, nil
+}
+
+// ecRecover returns the public key which created the given signature of the
+// given 32 byte hash.
+func ecRecover(hash, sig []byte) (*btcec.PublicKey, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("meta: signature hash must be 32 bytes")
+	}
+	if len(sig) != signatureLength {
+		return nil, errors.New("meta: invalid signature length")
+	}
+	if sig[64] > 3 {
+		return nil, errors.New("meta: invalid signature values")
+	}
+
+	// reject high S values so that signatures are not malleable
+	if s := new(big.Int).SetBytes(sig[32:64]); s.Cmp(secp256k1HalfN) > 0 {
+		return nil, errors.New("meta: invalid signature values")
+	}
+
+	compact := make([]byte, signatureLength)
+	compact[0] = sig[64] + compactSigMagicOffset
+	copy(compact[1:], sig[:64])
+	pub, _, err := btcec.RecoverCompact(btcec.S256(), compact, hash)
+	if err != nil {
+		return nil, errors.New("meta: invalid signature")
+	}
+	return pub, nil
+}
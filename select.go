@@ -0,0 +1,469 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+// Match is a value matched by a selector.
+type Match struct {
+	// Path is the resolved path of the value from the root of the graph
+	// (e.g. ["ResourceList", "SoundRecording", "0"]).
+	Path []string
+
+	// Value is the matched value, with links represented as *cid.Cid
+	// values (as returned by Graph.Get).
+	Value interface{}
+}
+
+// Select returns the values in the graph which match the given selector,
+// along with their resolved paths, in the order they appear in the graph
+// (with map keys sorted lexically).
+//
+// A selector is a slash separated path like those accepted by Get, where each
+// step is either a property name, a list index or one of:
+//
+//   *       each element of a list, or the value itself if it is not a list,
+//           which handles properties which are a single value if there is
+//           only one value but a list if there are many
+//   **      the value and all of its descendants (i.e. recursive descent),
+//           where an object linked to more than once is only descended
+//           into the first time it is reached
+//
+// Steps can also be followed by any number of bracketed expressions which
+// treat the value as a list (so a single value is a list of one element) and
+// select elements from it:
+//
+//   [2]          the element at index 2 (negative indexes count from the end)
+//   [1:3]        the elements from index 1 up to but not including index 3,
+//                with either index optional (e.g. [:5])
+//   [a/b]        the elements which have a value at the relative path a/b
+//   [a/b=x]      the elements whose value at a/b is x (x can be quoted)
+//   [a/b!=x]     the elements whose value at a/b is not x
+//
+// For example, to select the ISRCs of every SoundRecording in an ERN:
+//
+//   NewReleaseMessage/ResourceList/SoundRecording/*/SoundRecordingId/ISRC/@value
+//
+// Links are followed transparently, both when stepping into linked objects
+// and when evaluating predicates.
+func (g *Graph) Select(selector string) ([]*Match, error) {
+	steps, err := parseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	properties, err := g.root.Properties()
+	if err != nil {
+		return nil, err
+	}
+	s := &selection{get: g.get}
+	matches := []*Match{{Value: properties}}
+	for _, step := range steps {
+		var next []*Match
+		for _, m := range matches {
+			res, err := s.apply(step, m)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, res...)
+		}
+		matches = next
+	}
+
+	// return the root as an object like Get does
+	for _, m := range matches {
+		if len(m.Path) == 0 {
+			m.Value = g.root
+		}
+	}
+	return matches, nil
+}
+
+// selectorStep is a single step of a parsed selector.
+type selectorStep struct {
+	// name is either a property name, "*", "**" or empty if the step only
+	// has filters.
+	name string
+
+	filters []selectorFilter
+}
+
+// selectorFilter is a bracketed expression which selects elements of a
+// list.
+type selectorFilter struct {
+	// slice filters
+	isSlice    bool
+	isIndex    bool
+	start, end *int
+
+	// predicate filters
+	path  []string
+	op    string
+	value string
+}
+
+// parseSelector parses a selector into its steps.
+func parseSelector(selector string) ([]*selectorStep, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return ErrInvalidSelector{selector, fmt.Sprintf(format, args...)}
+	}
+	var steps []*selectorStep
+	s := strings.Trim(selector, "/")
+	for len(s) > 0 {
+		step := &selectorStep{}
+
+		// read the name up to the first '[' or '/'
+		i := strings.IndexAny(s, "[/")
+		if i == -1 {
+			i = len(s)
+		}
+		step.name, s = s[:i], s[i:]
+
+		// read any bracketed filters
+		for strings.HasPrefix(s, "[") {
+			end := closingBracket(s)
+			if end == -1 {
+				return nil, invalid("unterminated '['")
+			}
+			filter, err := parseSelectorFilter(s[1:end])
+			if err != nil {
+				return nil, invalid("%s", err)
+			}
+			step.filters = append(step.filters, filter)
+			s = s[end+1:]
+		}
+
+		if step.name == "" && len(step.filters) == 0 {
+			return nil, invalid("empty step")
+		}
+		if len(s) > 0 {
+			if s[0] != '/' {
+				return nil, invalid("unexpected %q after ']'", s[0])
+			}
+			s = s[1:]
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// closingBracket returns the index of the ']' which closes the '[' at the
+// start of s, skipping any which are quoted.
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseSelectorFilter parses the contents of a bracketed expression.
+func parseSelectorFilter(s string) (selectorFilter, error) {
+	var f selectorFilter
+	s = strings.TrimSpace(s)
+
+	// check for indexes and slices first, treating anything else as a
+	// predicate
+	if i, err := strconv.Atoi(s); err == nil {
+		f.isSlice, f.isIndex, f.start = true, true, &i
+		return f, nil
+	}
+	if start, end, ok := parseSlice(s); ok {
+		f.isSlice, f.start, f.end = true, start, end
+		return f, nil
+	}
+
+	path := s
+	if i := strings.Index(s, "="); i != -1 {
+		path, f.value, f.op = s[:i], strings.TrimSpace(s[i+1:]), "="
+		if strings.HasSuffix(path, "!") {
+			path, f.op = path[:len(path)-1], "!="
+		}
+		if n := len(f.value); n >= 2 && (f.value[0] == '"' || f.value[0] == '\'') && f.value[n-1] == f.value[0] {
+			f.value = f.value[1 : n-1]
+		}
+	}
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return f, fmt.Errorf("invalid predicate %q", s)
+	}
+	f.path = strings.Split(path, "/")
+	return f, nil
+}
+
+// parseSlice parses a slice like "1:3", ":5" or "-2:", returning false if s
+// is not a slice (e.g. if it is a property name like "ern:Title").
+func parseSlice(s string) (start, end *int, ok bool) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, nil, false
+	}
+	bounds := make([]*int, 2)
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, nil, false
+		}
+		bounds[i] = &n
+	}
+	return bounds[0], bounds[1], true
+}
+
+// selection evaluates selector steps, loading linked objects using get.
+type selection struct {
+	get func(*cid.Cid) (*Object, error)
+}
+
+// apply applies a selector step to a match.
+func (s *selection) apply(step *selectorStep, m *Match) ([]*Match, error) {
+	var matches []*Match
+	switch step.name {
+	case "":
+		matches = []*Match{m}
+	case "*":
+		list, err := s.elements(m)
+		if err != nil {
+			return nil, err
+		}
+		matches = list
+	case "**":
+		if err := s.descendants(m, make(map[string]bool), &matches); err != nil {
+			return nil, err
+		}
+	default:
+		child, ok, err := s.child(m, step.name)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			matches = []*Match{child}
+		}
+	}
+	for _, f := range step.filters {
+		var filtered []*Match
+		for _, m := range matches {
+			res, err := s.filter(f, m)
+			if err != nil {
+				return nil, err
+			}
+			filtered = append(filtered, res...)
+		}
+		matches = filtered
+	}
+	return matches, nil
+}
+
+// load returns the properties of the linked object if v is a link, and
+// otherwise returns v.
+func (s *selection) load(v interface{}) (interface{}, error) {
+	id, ok := v.(*cid.Cid)
+	if !ok {
+		return v, nil
+	}
+	obj, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Properties()
+}
+
+// child returns the property with the given name (or list element with the
+// given index) of the matched value.
+func (s *selection) child(m *Match, name string) (*Match, bool, error) {
+	v, err := s.load(m.Value)
+	if err != nil {
+		return nil, false, err
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		x, ok := v[name]
+		if !ok {
+			return nil, false, nil
+		}
+		return &Match{Path: appendPath(m.Path, name), Value: x}, true, nil
+	case []interface{}:
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, false, nil
+		}
+		return &Match{Path: appendPath(m.Path, name), Value: v[i]}, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// elements returns the elements of the matched value if it is a list, and
+// otherwise returns the match itself.
+func (s *selection) elements(m *Match) ([]*Match, error) {
+	list, ok := m.Value.([]interface{})
+	if !ok {
+		return []*Match{m}, nil
+	}
+	matches := make([]*Match, len(list))
+	for i, x := range list {
+		matches[i] = &Match{Path: appendPath(m.Path, strconv.Itoa(i)), Value: x}
+	}
+	return matches, nil
+}
+
+// descendants appends the match and all of its descendants to matches,
+// following links to objects which are not in visited, so that objects
+// linked to more than once are only descended into once.
+func (s *selection) descendants(m *Match, visited map[string]bool, matches *[]*Match) error {
+	*matches = append(*matches, m)
+	if id, ok := m.Value.(*cid.Cid); ok {
+		if visited[id.KeyString()] {
+			return nil
+		}
+		visited[id.KeyString()] = true
+	}
+	v, err := s.load(m.Value)
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := s.descendants(&Match{Path: appendPath(m.Path, key), Value: v[key]}, visited, matches); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, x := range v {
+			if err := s.descendants(&Match{Path: appendPath(m.Path, strconv.Itoa(i)), Value: x}, visited, matches); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filter applies a bracketed expression to the matched value, treating it
+// as a list.
+func (s *selection) filter(f selectorFilter, m *Match) ([]*Match, error) {
+	elements, err := s.elements(m)
+	if err != nil {
+		return nil, err
+	}
+	if f.isSlice {
+		n := len(elements)
+		start, end := 0, n
+		if f.start != nil {
+			start = *f.start
+			if start < 0 {
+				start += n
+			}
+		}
+		if f.isIndex {
+			if start < 0 || start >= n {
+				return nil, nil
+			}
+			return elements[start : start+1], nil
+		}
+		if f.end != nil {
+			end = *f.end
+			if end < 0 {
+				end += n
+			}
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end > n {
+			end = n
+		}
+		if start >= end {
+			return nil, nil
+		}
+		return elements[start:end], nil
+	}
+
+	var matches []*Match
+	for _, elem := range elements {
+		ok, err := s.predicate(f, elem)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, elem)
+		}
+	}
+	return matches, nil
+}
+
+// predicate evaluates a predicate filter against a matched value.
+func (s *selection) predicate(f selectorFilter, m *Match) (bool, error) {
+	cur := m
+	for _, key := range f.path {
+		child, ok, err := s.child(cur, key)
+		if err != nil {
+			return false, err
+		} else if !ok {
+			return f.op == "!=", nil
+		}
+		cur = child
+	}
+	switch f.op {
+	case "=":
+		return predicateValue(cur.Value) == f.value, nil
+	case "!=":
+		return predicateValue(cur.Value) != f.value, nil
+	default:
+		return true, nil
+	}
+}
+
+// predicateValue returns the string form of a value used to compare it with
+// a predicate, unwrapping @value objects.
+func predicateValue(v interface{}) string {
+	switch v := unwrapValue(v).(type) {
+	case string:
+		return v
+	case *cid.Cid:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,217 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+func TestGraphSelect(t *testing.T) {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *Object {
+		obj := MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	recording := func(isrc, title string) *Object {
+		return put(map[string]interface{}{
+			"@type":            "SoundRecording",
+			"SoundRecordingId": map[string]interface{}{"ISRC": map[string]interface{}{"@value": isrc}},
+			"ReferenceTitle":   map[string]interface{}{"TitleText": title},
+		})
+	}
+	r0 := recording("GBAAA0000001", "one")
+	r1 := recording("GBAAA0000002", "two")
+	r2 := recording("GBAAA0000003", "three")
+	single := put(map[string]interface{}{"SoundRecording": r0.Cid()})
+	multiple := put(map[string]interface{}{"SoundRecording": []*cid.Cid{r0.Cid(), r1.Cid(), r2.Cid()}})
+
+	type match struct {
+		path  string
+		value interface{}
+	}
+	for _, test := range []struct {
+		root     *Object
+		selector string
+		expected []match
+	}{
+		{
+			root:     single,
+			selector: "SoundRecording/*/SoundRecordingId/ISRC/@value",
+			expected: []match{{"SoundRecording/SoundRecordingId/ISRC/@value", "GBAAA0000001"}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording/*/SoundRecordingId/ISRC/@value",
+			expected: []match{
+				{"SoundRecording/0/SoundRecordingId/ISRC/@value", "GBAAA0000001"},
+				{"SoundRecording/1/SoundRecordingId/ISRC/@value", "GBAAA0000002"},
+				{"SoundRecording/2/SoundRecordingId/ISRC/@value", "GBAAA0000003"},
+			},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording/*",
+			expected: []match{
+				{"SoundRecording/0", r0.Cid()},
+				{"SoundRecording/1", r1.Cid()},
+				{"SoundRecording/2", r2.Cid()},
+			},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[1:]/ReferenceTitle/TitleText",
+			expected: []match{
+				{"SoundRecording/1/ReferenceTitle/TitleText", "two"},
+				{"SoundRecording/2/ReferenceTitle/TitleText", "three"},
+			},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[:1]/ReferenceTitle/TitleText",
+			expected: []match{{"SoundRecording/0/ReferenceTitle/TitleText", "one"}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[-1]/ReferenceTitle/TitleText",
+			expected: []match{{"SoundRecording/2/ReferenceTitle/TitleText", "three"}},
+		},
+		{
+			root:     single,
+			selector: "SoundRecording[0]/ReferenceTitle/TitleText",
+			expected: []match{{"SoundRecording/ReferenceTitle/TitleText", "one"}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording/1/ReferenceTitle/TitleText",
+			expected: []match{{"SoundRecording/1/ReferenceTitle/TitleText", "two"}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[SoundRecordingId/ISRC='GBAAA0000002']/ReferenceTitle/TitleText",
+			expected: []match{{"SoundRecording/1/ReferenceTitle/TitleText", "two"}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording/*[ReferenceTitle/TitleText!=two][0:5]/ReferenceTitle/TitleText",
+			expected: []match{
+				{"SoundRecording/0/ReferenceTitle/TitleText", "one"},
+				{"SoundRecording/2/ReferenceTitle/TitleText", "three"},
+			},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[@type=SoundRecording][ReferenceTitle]/ReferenceTitle/TitleText",
+			expected: []match{
+				{"SoundRecording/0/ReferenceTitle/TitleText", "one"},
+				{"SoundRecording/1/ReferenceTitle/TitleText", "two"},
+				{"SoundRecording/2/ReferenceTitle/TitleText", "three"},
+			},
+		},
+		{
+			root:     multiple,
+			selector: "**/TitleText",
+			expected: []match{
+				{"SoundRecording/0/ReferenceTitle/TitleText", "one"},
+				{"SoundRecording/1/ReferenceTitle/TitleText", "two"},
+				{"SoundRecording/2/ReferenceTitle/TitleText", "three"},
+			},
+		},
+		{
+			root:     single,
+			selector: "**[@value]",
+			expected: []match{{"SoundRecording/SoundRecordingId/ISRC", map[string]interface{}{"@value": "GBAAA0000001"}}},
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording/*/Missing",
+			expected: nil,
+		},
+		{
+			root:     multiple,
+			selector: "SoundRecording[5]",
+			expected: nil,
+		},
+	} {
+		matches, err := NewGraph(store, test.root).Select(test.selector)
+		if err != nil {
+			t.Fatalf("error selecting %s: %s", test.selector, err)
+		}
+		var actual []match
+		for _, m := range matches {
+			actual = append(actual, match{strings.Join(m.Path, "/"), m.Value})
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("unexpected matches for %s:\nexpected: %v\ngot:      %v", test.selector, test.expected, actual)
+		}
+	}
+
+	// check the root is returned as an object
+	matches, err := NewGraph(store, single).Select("**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj, ok := matches[0].Value.(*Object); !ok || !obj.Cid().Equals(single.Cid()) {
+		t.Fatalf("expected first match to be the root object, got %v", matches[0].Value)
+	}
+
+	// check a root which is not in the store can be selected from
+	missing := MustEncode(map[string]interface{}{"name": "missing", "child": single.Cid()})
+	matches, err = NewGraph(store, missing).Select("child/SoundRecording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+
+	// check ** only descends into an object linked to more than once the
+	// first time it is reached
+	shared := MustEncode(map[string]interface{}{"name": "shared"})
+	if err := store.Put(shared); err != nil {
+		t.Fatal(err)
+	}
+	links := MustEncode(map[string]interface{}{"a": shared.Cid(), "b": shared.Cid()})
+	matches, err = NewGraph(store, links).Select("**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, m := range matches {
+		paths = append(paths, strings.Join(m.Path, "/"))
+	}
+	if expected := []string{"", "a", "a/name", "b"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("unexpected ** paths:\nexpected: %q\ngot:      %q", expected, paths)
+	}
+
+	// check invalid selectors are rejected
+	for _, selector := range []string{"a//b", "a[0", "a[]", "a[0]b", "a[=x]"} {
+		if _, err := NewGraph(store, single).Select(selector); err == nil {
+			t.Fatalf("expected error parsing selector %q", selector)
+		}
+	}
+}
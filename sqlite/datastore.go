@@ -0,0 +1,379 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+// The sqlite package provides a datastore which stores entries in a single
+// SQLite3 database file, avoiding the overhead of the one file per entry used
+// by the fs datastore when storing millions of objects.
+//
+// Typical usage would be:
+//
+//   ds, err := sqlite.NewDatastore("meta.db")
+//   if err != nil {
+//     return err
+//   }
+//   defer ds.Close()
+//   store := meta.NewStore(ds)
+//
+package sqlite
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Datastore is a datastore.Batching implementation which stores entries in a
+// SQLite3 database using write-ahead logging so that reads can proceed
+// concurrently with writes.
+type Datastore struct {
+	db   *sql.DB
+	path string
+}
+
+// NewDatastore opens the SQLite3 database at the given path, creating and
+// migrating it if necessary, and returns a Datastore which uses it.
+//
+// The path can include a query string of go-sqlite3 connection parameters
+// (e.g. "meta.db?_busy_timeout=5000"), but _txlock is always set to
+// immediate.
+func NewDatastore(path string) (*Datastore, error) {
+	dsn := path
+	params := make(url.Values)
+	if i := strings.Index(path, "?"); i != -1 {
+		var err error
+		dsn = path[:i]
+		params, err = url.ParseQuery(path[i+1:])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// use immediate transactions so that concurrent batches wait for the
+	// write lock rather than failing when upgrading a read lock
+	params.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite3", dsn+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrations.Run(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Datastore{db: db, path: path}, nil
+}
+
+// Put stores the given value, which must be a byte slice, with the given
+// key.
+func (d *Datastore) Put(key datastore.Key, value interface{}) error {
+	return put(d.db, key, value)
+}
+
+// Get returns the value stored with the given key.
+func (d *Datastore) Get(key datastore.Key) (interface{}, error) {
+	return get(d.db, key)
+}
+
+// Has returns whether a value is stored with the given key.
+func (d *Datastore) Has(key datastore.Key) (bool, error) {
+	return has(d.db, key)
+}
+
+// Delete deletes the value stored with the given key.
+func (d *Datastore) Delete(key datastore.Key) error {
+	return del(d.db, key)
+}
+
+// Query returns the entries matching the given query, using the database to
+// select keys with the query prefix and applying any filters and orders in
+// memory.
+func (d *Datastore) Query(q query.Query) (query.Results, error) {
+	return queryEntries(d.db, q)
+}
+
+// Txn runs fn in an immediate transaction, passing it a datastore which
+// reads and writes within the transaction. Since an immediate transaction
+// holds the database write lock, fn's reads and writes are isolated from
+// every other writer, including those in other processes.
+//
+// The transaction is committed if fn returns nil and rolled back otherwise.
+func (d *Datastore) Txn(fn func(datastore.Datastore) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&txDatastore{tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Batch returns a batch of puts and deletes which are applied in a single
+// transaction when committed.
+func (d *Datastore) Batch() (datastore.Batch, error) {
+	return &batch{db: d.db}, nil
+}
+
+// IsThreadSafe implements the datastore.ThreadSafeDatastore interface.
+func (d *Datastore) IsThreadSafe() {}
+
+// Close closes the underlying database.
+func (d *Datastore) Close() error {
+	return d.db.Close()
+}
+
+// txDatastore is a datastore which reads and writes within a transaction.
+type txDatastore struct {
+	tx *sql.Tx
+}
+
+func (t *txDatastore) Put(key datastore.Key, value interface{}) error {
+	return put(t.tx, key, value)
+}
+
+func (t *txDatastore) Get(key datastore.Key) (interface{}, error) {
+	return get(t.tx, key)
+}
+
+func (t *txDatastore) Has(key datastore.Key) (bool, error) {
+	return has(t.tx, key)
+}
+
+func (t *txDatastore) Delete(key datastore.Key) error {
+	return del(t.tx, key)
+}
+
+func (t *txDatastore) Query(q query.Query) (query.Results, error) {
+	return queryEntries(t.tx, q)
+}
+
+func get(q querier, key datastore.Key) (interface{}, error) {
+	var value []byte
+	err := q.QueryRow(`SELECT value FROM entry WHERE key = $1`, key.String()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, datastore.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func has(q querier, key datastore.Key) (bool, error) {
+	var n int
+	err := q.QueryRow(`SELECT COUNT(*) FROM entry WHERE key = $1`, key.String()).Scan(&n)
+	return n > 0, err
+}
+
+// orderedByKey returns whether the given orders are satisfied by ordering
+// entries by key, which queryEntries always does.
+func orderedByKey(orders []query.Order) bool {
+	for _, o := range orders {
+		if _, ok := o.(query.OrderByKey); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func queryEntries(db querier, q query.Query) (query.Results, error) {
+	sqlQuery := `SELECT key, value FROM entry`
+	if q.KeysOnly {
+		sqlQuery = `SELECT key, NULL FROM entry`
+	}
+	var args []interface{}
+	if q.Prefix != "" {
+		sqlQuery += ` WHERE key >= $1`
+		args = append(args, q.Prefix)
+		if end, ok := prefixEnd(q.Prefix); ok {
+			sqlQuery += ` AND key < $2`
+			args = append(args, end)
+		}
+	}
+	sqlQuery += ` ORDER BY key`
+
+	// apply the offset and limit in the database unless results need to
+	// be filtered or ordered by something other than the key first
+	naive := len(q.Filters) > 0 || !orderedByKey(q.Orders)
+	if !naive {
+		if q.Limit > 0 {
+			sqlQuery += ` LIMIT ` + strconv.Itoa(q.Limit)
+		} else if q.Offset > 0 {
+			sqlQuery += ` LIMIT -1`
+		}
+		if q.Offset > 0 {
+			sqlQuery += ` OFFSET ` + strconv.Itoa(q.Offset)
+		}
+	}
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	results := query.ResultsFromIterator(q, query.Iterator{
+		Next: func() (query.Result, bool) {
+			if !rows.Next() {
+				if err := rows.Err(); err != nil {
+					return query.Result{Error: err}, true
+				}
+				return query.Result{}, false
+			}
+			var key string
+			var value []byte
+			if err := rows.Scan(&key, &value); err != nil {
+				return query.Result{Error: err}, true
+			}
+			entry := query.Entry{Key: key}
+			if q.KeysOnly {
+				entry.Value = query.NotFetched
+			} else {
+				entry.Value = value
+			}
+			return query.Result{Entry: entry}, true
+		},
+		Close: rows.Close,
+	})
+	if !naive {
+		return results, nil
+	}
+
+	// read the entries up front, since the naive query functions read
+	// from the results in another goroutine which races with the lazy
+	// initialisation of iterator based results
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	results = query.ResultsWithEntries(q, entries)
+	for _, f := range q.Filters {
+		results = query.NaiveFilter(results, f)
+	}
+	for _, o := range q.Orders {
+		results = query.NaiveOrder(results, o)
+	}
+	if q.Offset > 0 {
+		results = query.NaiveOffset(results, q.Offset)
+	}
+	if q.Limit > 0 {
+		results = query.NaiveLimit(results, q.Limit)
+	}
+	return results, nil
+}
+
+// batch buffers puts and deletes until Commit is called.
+type batch struct {
+	db  *sql.DB
+	ops []func(execer) error
+}
+
+func (b *batch) Put(key datastore.Key, value interface{}) error {
+	if _, ok := value.([]byte); !ok {
+		return datastore.ErrInvalidType
+	}
+	b.ops = append(b.ops, func(e execer) error {
+		return put(e, key, value)
+	})
+	return nil
+}
+
+func (b *batch) Delete(key datastore.Key) error {
+	b.ops = append(b.ops, func(e execer) error {
+		if err := del(e, key); err != nil && err != datastore.ErrNotFound {
+			return err
+		}
+		return nil
+	})
+	return nil
+}
+
+func (b *batch) Commit() error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, op := range b.ops {
+		if err := op(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	b.ops = nil
+	return nil
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	execer
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func put(e execer, key datastore.Key, value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		return datastore.ErrInvalidType
+	}
+	_, err := e.Exec(
+		`INSERT OR REPLACE INTO entry (key, value) VALUES ($1, $2)`,
+		key.String(), data,
+	)
+	return err
+}
+
+func del(e execer, key datastore.Key) error {
+	res, err := e.Exec(`DELETE FROM entry WHERE key = $1`, key.String())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return datastore.ErrNotFound
+	}
+	return nil
+}
+
+// prefixEnd returns the smallest string which is greater than every string
+// with the given prefix, returning false if there is no such string.
+func prefixEnd(prefix string) (string, bool) {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,284 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/meta-network/go-meta"
+)
+
+func newTestDatastore(t *testing.T) (*Datastore, func()) {
+	dir, err := ioutil.TempDir("", "sqlite-datastore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := NewDatastore(filepath.Join(dir, "meta.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return ds, func() {
+		ds.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// TestDatastoreParams tests opening a datastore with a path which includes
+// connection parameters.
+func TestDatastoreParams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite-datastore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ds, err := NewDatastore(filepath.Join(dir, "meta.db") + "?_busy_timeout=5000&_txlock=deferred")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ds.Close()
+	if err := ds.Put(datastore.NewKey("foo"), []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "meta.db")); err != nil {
+		t.Fatalf("expected database file to be created: %s", err)
+	}
+}
+
+func TestDatastore(t *testing.T) {
+	ds, cleanup := newTestDatastore(t)
+	defer cleanup()
+
+	// check basic operations
+	key := datastore.NewKey("/a/b")
+	if _, err := ds.Get(key); err != datastore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := ds.Put(key, "string"); err != datastore.ErrInvalidType {
+		t.Fatalf("expected ErrInvalidType, got %v", err)
+	}
+	if err := ds.Put(key, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ds.Get(key); err != nil {
+		t.Fatal(err)
+	} else if string(v.([]byte)) != "value" {
+		t.Fatalf("unexpected value: %q", v)
+	}
+	if has, err := ds.Has(key); err != nil || !has {
+		t.Fatalf("expected Has to return true, got %v %v", has, err)
+	}
+	if err := ds.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Delete(key); err != datastore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// check batches are applied on commit
+	b, err := ds.Batch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"/x/1", "/x/2", "/x/3", "/xy", "/y/1"} {
+		if err := b.Put(datastore.NewKey(k), []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Delete(datastore.NewKey("/x/3")); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := ds.Has(datastore.NewKey("/x/1")); has {
+		t.Fatal("expected batch to not be applied before commit")
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// check prefix queries with offsets and limits
+	keys := func(q query.Query) []string {
+		results, err := ds.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := results.Rest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := make([]string, len(entries))
+		for i, entry := range entries {
+			keys[i] = entry.Key
+			if !q.KeysOnly && string(entry.Value.([]byte)) != entry.Key {
+				t.Fatalf("unexpected value for %s: %q", entry.Key, entry.Value)
+			}
+		}
+		return keys
+	}
+	for _, test := range []struct {
+		query    query.Query
+		expected []string
+	}{
+		{query.Query{}, []string{"/x/1", "/x/2", "/xy", "/y/1"}},
+		{query.Query{Prefix: "/x"}, []string{"/x/1", "/x/2", "/xy"}},
+		{query.Query{Prefix: "/x/", KeysOnly: true}, []string{"/x/1", "/x/2"}},
+		{query.Query{Offset: 1, Limit: 2}, []string{"/x/2", "/xy"}},
+		{query.Query{Offset: 3}, []string{"/y/1"}},
+		{query.Query{Filters: []query.Filter{query.FilterKeyCompare{Op: query.NotEqual, Key: "/xy"}}, Offset: 1}, []string{"/x/2", "/y/1"}},
+	} {
+		if actual := keys(test.query); !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("unexpected keys for query %+v:\nexpected: %v\nactual:   %v", test.query, test.expected, actual)
+		}
+	}
+}
+
+// TestStore tests using the datastore as the underlying datastore of a META
+// store.
+func TestStore(t *testing.T) {
+	ds, cleanup := newTestDatastore(t)
+	defer cleanup()
+	store := meta.NewStore(ds)
+
+	var expected []string
+	for _, name := range []string{"one", "two", "three"} {
+		obj := meta.MustEncode(map[string]string{"@type": "Artist", "name": name})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, obj.Cid().String())
+	}
+	sort.Strings(expected)
+	if err := store.Pin("artists", meta.MustEncode(map[string]string{"@type": "Artist", "name": "one"}).Cid()); err != nil {
+		t.Fatal(err)
+	}
+
+	// check iterating the store only returns objects
+	iter, err := store.AllCids(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	var actual []string
+	for iter.Next() {
+		obj, err := store.Get(iter.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, obj.Cid().String())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(actual)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected CIDs:\nexpected: %v\nactual:   %v", expected, actual)
+	}
+
+	// check the type index
+	ids, err := store.FindByType("Artist", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %d artists, got %d", len(expected), len(ids))
+	}
+
+	// check the offset and limit are applied in CID order
+	page, err := store.FindByType("Artist", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page, ids[1:3]) {
+		t.Fatalf("unexpected page of artists:\nexpected: %v\nactual:   %v", ids[1:3], page)
+	}
+}
+
+// TestRefsUpdate tests that ref updates from separate stores sharing the
+// same database, as would be the case for separate processes, are atomic.
+func TestRefsUpdate(t *testing.T) {
+	ds, cleanup := newTestDatastore(t)
+	defer cleanup()
+	store := meta.NewStore(ds)
+	base := meta.MustEncode(map[string]string{"name": "base"})
+	if err := store.Put(base); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Refs().Set("latest", base.Cid(), "create"); err != nil {
+		t.Fatal(err)
+	}
+
+	// open a separate datastore for each updater so that they do not
+	// share the in-process refs lock
+	const n = 8
+	stores := make([]*meta.Store, n)
+	for i := range stores {
+		other, err := NewDatastore(ds.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+		stores[i] = meta.NewStore(other)
+		obj := meta.MustEncode(map[string]interface{}{"name": "update", "n": i})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i, s := range stores {
+		wg.Add(1)
+		go func(i int, s *meta.Store) {
+			defer wg.Done()
+			obj := meta.MustEncode(map[string]interface{}{"name": "update", "n": i})
+			errs <- s.Refs().Update("latest", base.Cid(), obj.Cid(), "update")
+		}(i, s)
+	}
+	wg.Wait()
+	close(errs)
+
+	// check exactly one update succeeded and the others conflicted
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		} else if _, ok := err.(meta.ErrRefConflict); !ok {
+			t.Fatal(err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected 1 update to succeed, got %d", succeeded)
+	}
+	log, err := store.Refs().Log("latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(log))
+	}
+}
@@ -0,0 +1,38 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package sqlite
+
+import "github.com/meta-network/go-meta/migrate"
+
+// migrations is a set of database migrations to run on a SQLite3 database
+// to prepare it for storing datastore entries.
+var migrations = migrate.NewMigrations()
+
+func init() {
+	// migration 1 creates a table of datastore keys and values, with the
+	// primary key index being used for prefix queries
+	migrations.Add(1, `
+CREATE TABLE entry (
+	key   text NOT NULL PRIMARY KEY,
+	value blob NOT NULL
+);
+`,
+	)
+}
@@ -0,0 +1,171 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// typesPrefix is the datastore namespace which the type index is stored
+// under, with an empty entry at /types/<type>/<cid> for each object which
+// has a @type.
+//
+// The index costs an extra datastore key for each typed object (an empty
+// file with the fs datastore), which is written in the same batch as the
+// object (a single transaction with the sqlite datastore), and an extra
+// delete when the object is deleted.
+var typesPrefix = datastore.NewKey("types")
+
+// typePrefix returns the datastore namespace which CIDs of objects with the
+// given type are stored under, escaping the type so that types containing
+// slashes (e.g. URIs) are a single key component.
+func typePrefix(typ string) datastore.Key {
+	return typesPrefix.ChildString(url.PathEscape(typ))
+}
+
+// putObject adds the object, and its type index entry if it has a @type, to
+// the given datastore batch so that they are written together.
+func (s *Store) putObject(batch datastore.Batch, obj *Object) error {
+	if err := batch.Put(s.key(obj.Cid()), obj.RawData()); err != nil {
+		return err
+	}
+	if obj.Type() == "" {
+		return nil
+	}
+	return batch.Put(typePrefix(obj.Type()).ChildString(obj.Cid().String()), []byte{})
+}
+
+// typeIndexKeys returns the type index keys of the object with the given
+// CID, searching the whole type index if the object cannot be decoded to
+// get its @type (e.g. because it is corrupt).
+func (s *Store) typeIndexKeys(id *cid.Cid) ([]datastore.Key, error) {
+	obj, err := s.Get(id)
+	if err == datastore.ErrNotFound {
+		return nil, nil
+	} else if err == nil {
+		if obj.Type() == "" {
+			return nil, nil
+		}
+		return []datastore.Key{typePrefix(obj.Type()).ChildString(id.String())}, nil
+	}
+	results, err := s.store.Query(query.Query{Prefix: typesPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	var keys []datastore.Key
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if key.Name() == id.String() && key.Parent().Parent().Equal(typesPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// FindByType returns the CIDs of objects in the store with the given @type,
+// ordered by CID string and starting at the given offset. At most limit CIDs
+// are returned unless limit is zero.
+//
+// The ordering and offset are part of the datastore query so that
+// datastores which support them (e.g. the sqlite datastore) can apply them
+// without reading every entry, and reading stops once limit CIDs have been
+// found.
+func (s *Store) FindByType(typ string, offset, limit int) ([]*cid.Cid, error) {
+	prefix := typePrefix(typ)
+	results, err := s.store.Query(query.Query{
+		Prefix:   prefix.String() + "/",
+		Orders:   []query.Order{query.OrderByKey{}},
+		Offset:   offset,
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+	var ids []*cid.Cid
+	for res := range results.Next() {
+		if res.Error != nil {
+			return nil, res.Error
+		}
+		key := datastore.NewKey(res.Key)
+		if !key.Parent().Equal(prefix) {
+			continue
+		}
+		id, err := cid.Decode(key.Name())
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+		if limit > 0 && len(ids) == limit {
+			break
+		}
+	}
+	return ids, nil
+}
+
+// RebuildTypeIndex rebuilds the type index from the objects in the store,
+// which is needed for stores written before the index existed.
+func (s *Store) RebuildTypeIndex(ctx context.Context) error {
+	results, err := s.store.Query(query.Query{Prefix: typesPrefix.String(), KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		key := datastore.NewKey(entry.Key)
+		if !typesPrefix.IsAncestorOf(key) {
+			continue
+		}
+		if err := s.store.Delete(key); err != nil && err != datastore.ErrNotFound {
+			return err
+		}
+	}
+
+	iter, err := s.AllCids(ctx)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.Next() {
+		obj, err := s.Get(iter.Cid())
+		if err != nil {
+			return err
+		}
+		if obj.Type() == "" {
+			continue
+		}
+		if err := s.store.Put(typePrefix(obj.Type()).ChildString(obj.Cid().String()), []byte{}); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
@@ -0,0 +1,120 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+)
+
+func TestTypeIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meta-type-index-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFSStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// store some recordings and an object with a URI type
+	var recordings []*cid.Cid
+	for _, title := range []string{"one", "two", "three"} {
+		obj := MustEncode(map[string]string{"@type": "SoundRecording", "title": title})
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		recordings = append(recordings, obj.Cid())
+	}
+	uriType := "http://schema.org/MusicRecording"
+	other := MustEncode(map[string]string{"@type": uriType})
+	if err := store.Put(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(MustEncode(map[string]string{"title": "untyped"})); err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(typ string, offset, limit int) []string {
+		ids, err := store.FindByType(typ, offset, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := make([]string, len(ids))
+		for i, id := range ids {
+			s[i] = id.String()
+		}
+		return s
+	}
+	all := find("SoundRecording", 0, 0)
+	if len(all) != len(recordings) {
+		t.Fatalf("expected %d recordings, got %d", len(recordings), len(all))
+	}
+	for _, id := range recordings {
+		if !containsString(all, id.String()) {
+			t.Fatalf("expected to find recording %s", id)
+		}
+	}
+	if ids := find(uriType, 0, 0); len(ids) != 1 || ids[0] != other.Cid().String() {
+		t.Fatalf("unexpected objects of type %s: %v", uriType, ids)
+	}
+	if ids := find("Sound", 0, 0); len(ids) != 0 {
+		t.Fatalf("expected no objects of type Sound, got %v", ids)
+	}
+
+	// check pagination
+	page1, page2 := find("SoundRecording", 0, 2), find("SoundRecording", 2, 2)
+	if len(page1) != 2 || len(page2) != 1 || page1[0] != all[0] || page1[1] != all[1] || page2[0] != all[2] {
+		t.Fatalf("unexpected pages: %v %v", page1, page2)
+	}
+
+	// check deleted objects are removed from the index
+	if err := store.Delete(recordings[0]); err != nil {
+		t.Fatal(err)
+	}
+	if ids := find("SoundRecording", 0, 0); len(ids) != 2 || containsString(ids, recordings[0].String()) {
+		t.Fatalf("unexpected recordings after delete: %v", ids)
+	}
+
+	// check the index can be rebuilt
+	if err := store.RebuildTypeIndex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ids := find("SoundRecording", 0, 0); len(ids) != 2 {
+		t.Fatalf("expected 2 recordings after rebuild, got %d", len(ids))
+	}
+	if ids := find(uriType, 0, 0); len(ids) != 1 {
+		t.Fatalf("expected 1 object of type %s after rebuild, got %d", uriType, len(ids))
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
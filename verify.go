@@ -0,0 +1,203 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// quarantinePrefix is the datastore namespace which corrupt objects are moved
+// to when verifying a store with VerifyOptions.Quarantine set.
+var quarantinePrefix = datastore.NewKey("quarantine")
+
+// ProblemKind is the kind of problem found when verifying a store.
+type ProblemKind string
+
+const (
+	// ProblemCorrupt means the stored data does not hash to its CID or
+	// cannot be decoded.
+	ProblemCorrupt ProblemKind = "corrupt"
+
+	// ProblemInvalidCodec means the object's CID does not have the
+	// DAG-CBOR codec.
+	ProblemInvalidCodec ProblemKind = "invalid codec"
+
+	// ProblemDanglingLink means the object links to an object which is not
+	// in the store.
+	ProblemDanglingLink ProblemKind = "dangling link"
+)
+
+// Problem is a problem with a stored object found by Store.Verify.
+type Problem struct {
+	// Cid is the CID of the object which has the problem.
+	Cid *cid.Cid
+
+	// Kind is the kind of problem.
+	Kind ProblemKind
+
+	// Link is the path and CID of the missing object for a
+	// ProblemDanglingLink.
+	Link *Link
+
+	// Err is the underlying error for a ProblemCorrupt or
+	// ProblemInvalidCodec.
+	Err error
+}
+
+func (p *Problem) String() string {
+	if p.Kind == ProblemDanglingLink {
+		return fmt.Sprintf("%s: %s: %s", p.Cid, p.Kind, p.Link.Cid)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Cid, p.Kind, p.Err)
+}
+
+// VerifyOptions are options which control how Store.Verify checks objects.
+type VerifyOptions struct {
+	// Quarantine determines whether to move corrupt objects out of the
+	// store so that they are no longer returned from Get.
+	Quarantine bool
+}
+
+// VerifyResult is the result of verifying a store.
+type VerifyResult struct {
+	// Checked is the number of objects which were checked.
+	Checked int64
+
+	// Problems are the problems which were found.
+	Problems []*Problem
+
+	// Quarantined are the CIDs of the corrupt objects which were moved
+	// to quarantine.
+	Quarantined []*cid.Cid
+}
+
+// Verify checks the integrity of every object in the store by rehashing its
+// data and checking it against its CID, checking it is a DAG-CBOR object and
+// checking that every object it links to is also in the store.
+func (s *Store) Verify(ctx context.Context, opts *VerifyOptions) (*VerifyResult, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+
+	iter, err := s.AllCids(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	result := &VerifyResult{}
+	var corrupt []*cid.Cid
+	exists := make(map[string]bool)
+	for iter.Next() {
+		id := iter.Cid()
+		result.Checked++
+		problems, err := s.verify(id, exists)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range problems {
+			if p.Kind == ProblemCorrupt {
+				corrupt = append(corrupt, id)
+			}
+		}
+		result.Problems = append(result.Problems, problems...)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	// quarantine corrupt objects after iterating so that the datastore
+	// is not modified whilst it is being iterated
+	if !opts.Quarantine {
+		return result, nil
+	}
+	for _, id := range corrupt {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := s.quarantine(id); err != nil {
+			return nil, err
+		}
+		result.Quarantined = append(result.Quarantined, id)
+	}
+	return result, nil
+}
+
+// verify checks the object with the given CID, using exists to cache whether
+// linked objects are in the store.
+func (s *Store) verify(id *cid.Cid, exists map[string]bool) ([]*Problem, error) {
+	data, err := s.store.Get(s.key(id))
+	if err != nil {
+		return nil, err
+	}
+	v, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("meta: unexpected value type for %s: %T", id, data)
+	}
+	block, err := NewBlock(id, v)
+	if err != nil {
+		return []*Problem{{Cid: id, Kind: ProblemCorrupt, Err: err}}, nil
+	}
+	if block.Codec() != cid.DagCBOR {
+		return []*Problem{{Cid: id, Kind: ProblemInvalidCodec, Err: ErrInvalidCodec{block.Codec()}}}, nil
+	}
+	obj, err := NewObjectFromBlock(block)
+	if err != nil {
+		return []*Problem{{Cid: id, Kind: ProblemCorrupt, Err: err}}, nil
+	}
+	links, err := obj.Links()
+	if err != nil {
+		return []*Problem{{Cid: id, Kind: ProblemCorrupt, Err: err}}, nil
+	}
+	var problems []*Problem
+	for _, link := range links {
+		key := link.Cid.KeyString()
+		has, ok := exists[key]
+		if !ok {
+			has, err = s.Has(link.Cid)
+			if err != nil {
+				return nil, err
+			}
+			exists[key] = has
+		}
+		if !has {
+			problems = append(problems, &Problem{Cid: id, Kind: ProblemDanglingLink, Link: link})
+		}
+	}
+	return problems, nil
+}
+
+// quarantine copies the data of the object with the given CID to the
+// quarantine namespace and then deletes the object from the store, which
+// also removes it from the cache and the indexes.
+func (s *Store) quarantine(id *cid.Cid) error {
+	key := s.key(id)
+	data, err := s.store.Get(key)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(quarantinePrefix.Child(key), data); err != nil {
+		return err
+	}
+	return s.Delete(id)
+}
@@ -0,0 +1,159 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/multiformats/go-multihash"
+)
+
+func TestVerify(t *testing.T) {
+	graph := testGraph(t)
+	store := graph.store
+
+	// check the graph has no problems
+	res, err := store.Verify(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checked != 4 || len(res.Problems) != 0 {
+		t.Fatalf("expected 4 objects with no problems, got %d objects with problems %v", res.Checked, res.Problems)
+	}
+
+	// store a corrupt object (simulating a partial write)
+	corrupt := MustEncode(map[string]string{"name": "corrupt"})
+	data := corrupt.RawData()
+	if err := store.store.Put(store.key(corrupt.Cid()), data[:len(data)-1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// store a raw object
+	raw, err := cid.Prefix{
+		Version:  1,
+		Codec:    cid.Raw,
+		MhType:   multihash.SHA2_256,
+		MhLength: -1,
+	}.Sum([]byte("raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.store.Put(store.key(raw), []byte("raw")); err != nil {
+		t.Fatal(err)
+	}
+
+	// store an object which links to a missing object
+	missing := MustEncode(map[string]string{"name": "missing"})
+	dangling := MustEncode(map[string]interface{}{"name": "dangling", "child": missing.Cid()})
+	if err := store.Put(dangling); err != nil {
+		t.Fatal(err)
+	}
+
+	// check the problems are found and the corrupt object is quarantined
+	res, err = store.Verify(context.Background(), &VerifyOptions{Quarantine: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checked != 7 {
+		t.Fatalf("expected 7 objects to be checked, got %d", res.Checked)
+	}
+	problems := make(map[string]*Problem, len(res.Problems))
+	for _, p := range res.Problems {
+		problems[p.Cid.String()] = p
+	}
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", res.Problems)
+	}
+	for _, test := range []struct {
+		id   *cid.Cid
+		kind ProblemKind
+	}{
+		{corrupt.Cid(), ProblemCorrupt},
+		{raw, ProblemInvalidCodec},
+		{dangling.Cid(), ProblemDanglingLink},
+	} {
+		p, ok := problems[test.id.String()]
+		if !ok {
+			t.Fatalf("expected problem with %s", test.id)
+		}
+		if p.Kind != test.kind {
+			t.Fatalf("expected %s to have problem %q, got %q", test.id, test.kind, p.Kind)
+		}
+	}
+	if p := problems[dangling.Cid().String()]; !p.Link.Cid.Equals(missing.Cid()) {
+		t.Fatalf("expected dangling link to %s, got %s", missing.Cid(), p.Link.Cid)
+	}
+	if len(res.Quarantined) != 1 || !res.Quarantined[0].Equals(corrupt.Cid()) {
+		t.Fatalf("expected %s to be quarantined, got %v", corrupt.Cid(), res.Quarantined)
+	}
+	if has, err := store.Has(corrupt.Cid()); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatal("expected quarantined object to be removed from the store")
+	}
+}
+
+// TestVerifyQuarantine tests that a quarantined object is removed from the
+// cache and the type index.
+func TestVerifyQuarantine(t *testing.T) {
+	store := NewCachedStore(datastore.NewMapDatastore(), 10)
+	obj := MustEncode(map[string]string{"@type": "Person", "name": "corrupt"})
+	if err := store.Put(obj); err != nil {
+		t.Fatal(err)
+	}
+
+	// load the object into the cache and then corrupt it
+	if _, err := store.Get(obj.Cid()); err != nil {
+		t.Fatal(err)
+	}
+	data := obj.RawData()
+	if err := store.store.Put(store.key(obj.Cid()), data[:len(data)-1]); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := store.Verify(context.Background(), &VerifyOptions{Quarantine: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Quarantined) != 1 || !res.Quarantined[0].Equals(obj.Cid()) {
+		t.Fatalf("expected %s to be quarantined, got %v", obj.Cid(), res.Quarantined)
+	}
+	if _, err := store.Get(obj.Cid()); err != datastore.ErrNotFound {
+		t.Fatalf("expected quarantined object to not be found, got %v", err)
+	}
+	if ids, err := store.FindByType("Person", 0, 0); err != nil {
+		t.Fatal(err)
+	} else if len(ids) != 0 {
+		t.Fatalf("expected no objects with type Person, got %v", ids)
+	}
+	if has, err := store.store.Has(typePrefix("Person").ChildString(obj.Cid().String())); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Fatal("expected quarantined object to be removed from the type index")
+	}
+	if has, err := store.store.Has(quarantinePrefix.Child(store.key(obj.Cid()))); err != nil {
+		t.Fatal(err)
+	} else if !has {
+		t.Fatal("expected quarantined object data to be kept")
+	}
+}
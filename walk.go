@@ -0,0 +1,194 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs/go-datastore"
+)
+
+// SkipLinks is used as a return value from WalkFunc to indicate that the
+// links of the object being visited should not be followed.
+var SkipLinks = errors.New("meta: skip links")
+
+// WalkFunc is the type of function called by Graph.Walk for each object
+// reachable from the root of the graph.
+//
+// The path is the path from the root object to the visited object (which is
+// empty for the root object itself) and depth is the number of links which
+// were followed to reach it.
+//
+// If the function returns SkipLinks then the links of the object are not
+// followed, any other error stops the walk and is returned from Walk.
+type WalkFunc func(path []string, obj *Object, depth int) error
+
+// WalkOrder is the order in which Graph.Walk visits objects.
+type WalkOrder int
+
+const (
+	// BreadthFirst visits all objects at a given depth before visiting
+	// any objects at the next depth.
+	BreadthFirst WalkOrder = iota
+
+	// DepthFirst visits all objects reachable from an object before
+	// visiting its siblings.
+	DepthFirst
+)
+
+// WalkOptions are options which control how Graph.Walk traverses a graph.
+type WalkOptions struct {
+	// Order is the order in which objects are visited.
+	Order WalkOrder
+
+	// MaxDepth is the maximum number of links to follow from the root
+	// object, with zero meaning no limit.
+	MaxDepth int
+
+	// IgnoreMissing determines whether to ignore links to objects which
+	// are not in the store (e.g. the XML schema contexts of an ERN)
+	// rather than stopping the walk with an error.
+	IgnoreMissing bool
+}
+
+// Walk calls walkFn for the root object and every object reachable from it by
+// following links, in the order given by the options (breadth-first if opts
+// is nil).
+//
+// Each object is visited at most once, so sub-graphs which are linked more
+// than once are only visited using the first path which reaches them.
+func (g *Graph) Walk(ctx context.Context, walkFn WalkFunc, opts *WalkOptions) error {
+	if opts == nil {
+		opts = &WalkOptions{}
+	}
+	w := &walker{
+		store:  g.store,
+		walkFn: walkFn,
+		opts:   opts,
+		seen:   map[string]struct{}{g.root.Cid().KeyString(): {}},
+	}
+	var err error
+	switch opts.Order {
+	case BreadthFirst:
+		err = w.breadthFirst(ctx, g.root)
+	case DepthFirst:
+		err = w.depthFirst(ctx, nil, g.root, 0)
+	default:
+		return errors.New("meta: unknown walk order")
+	}
+	if err == SkipLinks {
+		err = nil
+	}
+	return err
+}
+
+// walker keeps track of the objects visited during a walk.
+type walker struct {
+	store  *Store
+	walkFn WalkFunc
+	opts   *WalkOptions
+	seen   map[string]struct{}
+}
+
+// walkItem is an object which has been reached at a particular path.
+type walkItem struct {
+	path  []string
+	obj   *Object
+	depth int
+}
+
+// visit calls the walk function for the given object and returns the links
+// which should be followed from it.
+func (w *walker) visit(ctx context.Context, item *walkItem) ([]*Link, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := w.walkFn(item.path, item.obj, item.depth); err == SkipLinks {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if w.opts.MaxDepth > 0 && item.depth >= w.opts.MaxDepth {
+		return nil, nil
+	}
+	return item.obj.Links()
+}
+
+// next loads the object a link points at, returning nil if it has already
+// been seen (or is missing and the walk is ignoring missing objects).
+func (w *walker) next(parent *walkItem, link *Link) (*walkItem, error) {
+	key := link.Cid.KeyString()
+	if _, ok := w.seen[key]; ok {
+		return nil, nil
+	}
+	w.seen[key] = struct{}{}
+	obj, err := w.store.Get(link.Cid)
+	if err == datastore.ErrNotFound && w.opts.IgnoreMissing {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	path := make([]string, 0, len(parent.path)+len(link.Path))
+	path = append(path, parent.path...)
+	path = append(path, link.Path...)
+	return &walkItem{path: path, obj: obj, depth: parent.depth + 1}, nil
+}
+
+func (w *walker) breadthFirst(ctx context.Context, root *Object) error {
+	queue := []*walkItem{{obj: root}}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		links, err := w.visit(ctx, item)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			child, err := w.next(item, link)
+			if err != nil {
+				return err
+			} else if child != nil {
+				queue = append(queue, child)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *walker) depthFirst(ctx context.Context, path []string, obj *Object, depth int) error {
+	item := &walkItem{path: path, obj: obj, depth: depth}
+	links, err := w.visit(ctx, item)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		child, err := w.next(item, link)
+		if err != nil {
+			return err
+		} else if child == nil {
+			continue
+		}
+		if err := w.depthFirst(ctx, child.path, child.obj, child.depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
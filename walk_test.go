@@ -0,0 +1,127 @@
+// This file is part of the go-meta library.
+//
+// Copyright (C) 2017 JAAK MUSIC LTD
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// If you have any questions please contact yo@jaak.io
+
+package meta
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+)
+
+// testGraph returns a graph of people where the grandchild is shared by
+// both children.
+func testGraph(t *testing.T) *Graph {
+	store := NewStore(datastore.NewMapDatastore())
+	put := func(v interface{}) *Object {
+		obj := MustEncode(v)
+		if err := store.Put(obj); err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	grandchild := put(map[string]interface{}{"name": "grandchild"})
+	child0 := put(map[string]interface{}{"name": "child0", "children": []*cid.Cid{grandchild.Cid()}})
+	child1 := put(map[string]interface{}{"name": "child1", "children": []*cid.Cid{grandchild.Cid()}})
+	parent := put(map[string]interface{}{"name": "parent", "children": []*cid.Cid{child0.Cid(), child1.Cid()}})
+	return NewGraph(store, parent)
+}
+
+func TestGraphWalk(t *testing.T) {
+	graph := testGraph(t)
+
+	walk := func(opts *WalkOptions, skip string) []string {
+		var visited []string
+		err := graph.Walk(context.Background(), func(path []string, obj *Object, depth int) error {
+			name, err := obj.GetString("name")
+			if err != nil {
+				return err
+			}
+			visited = append(visited, name+"@"+strings.Join(path, "/"))
+			if name == skip {
+				return SkipLinks
+			}
+			return nil
+		}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return visited
+	}
+
+	for _, test := range []struct {
+		opts     *WalkOptions
+		skip     string
+		expected []string
+	}{
+		{
+			opts: nil,
+			expected: []string{
+				"parent@",
+				"child0@children/0",
+				"child1@children/1",
+				"grandchild@children/0/children/0",
+			},
+		},
+		{
+			opts: &WalkOptions{Order: DepthFirst},
+			expected: []string{
+				"parent@",
+				"child0@children/0",
+				"grandchild@children/0/children/0",
+				"child1@children/1",
+			},
+		},
+		{
+			opts: &WalkOptions{MaxDepth: 1},
+			expected: []string{
+				"parent@",
+				"child0@children/0",
+				"child1@children/1",
+			},
+		},
+		{
+			opts: &WalkOptions{Order: DepthFirst},
+			skip: "child0",
+			expected: []string{
+				"parent@",
+				"child0@children/0",
+				"child1@children/1",
+				"grandchild@children/1/children/0",
+			},
+		},
+	} {
+		visited := walk(test.opts, test.skip)
+		if !reflect.DeepEqual(visited, test.expected) {
+			t.Fatalf("unexpected walk with opts %+v:\nexpected: %v\ngot:      %v", test.opts, test.expected, visited)
+		}
+	}
+
+	// check the walk stops when the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := graph.Walk(ctx, func([]string, *Object, int) error { return nil }, nil)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
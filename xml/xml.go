@@ -20,15 +20,31 @@
 package metaxml
 
 import (
+	"bufio"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/ipfs/go-cid"
 	"github.com/meta-network/go-meta"
 )
 
+const (
+	// namespaceKey is the property which stores the namespace URI of an
+	// element.
+	namespaceKey = "@namespace"
+
+	// childrenKey is the property which stores the names of an element's
+	// children in document order.
+	childrenKey = "@children"
+
+	// xmlNamespace is the namespace URI bound to the reserved xml prefix
+	// (e.g. in xml:lang attributes).
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
 // EncodeXMLSchema encodes an XML Schema document as a META object graph.
 func EncodeXMLSchema(src io.Reader, namespace, uri string) (*meta.Object, error) {
 	dec := xml.NewDecoder(src)
@@ -74,8 +90,29 @@ func EncodeXMLSchema(src io.Reader, namespace, uri string) (*meta.Object, error)
 	return meta.Encode(map[string]interface{}{"@context": context})
 }
 
+// EncodeXMLOptions are options which control how EncodeXML encodes a
+// document.
+type EncodeXMLOptions struct {
+	// Reversible stores each element's namespace URI in a @namespace
+	// property and, if it has children with different names, the order
+	// of its children as a list of their names in a @children property,
+	// so that the document can be rebuilt with DecodeXML.
+	//
+	// It is off by default since the extra properties change the CIDs of
+	// the encoded objects.
+	Reversible bool
+}
+
 // EncodeXML encodes an XML document as a META object graph.
-func EncodeXML(src io.Reader, context []*cid.Cid, callback func(*meta.Object) error) (*meta.Object, error) {
+//
+// Each element is encoded as an object with the element's local name as its
+// @type, its attributes as string properties, its child elements as links
+// (or lists of links if there is more than one child with the same name) and
+// its text as a @value property.
+func EncodeXML(src io.Reader, context []*cid.Cid, callback func(*meta.Object) error, opts *EncodeXMLOptions) (*meta.Object, error) {
+	if opts == nil {
+		opts = &EncodeXMLOptions{}
+	}
 	dec := xml.NewDecoder(src)
 
 	// read tokens until we find the root element (i.e. the first
@@ -93,7 +130,7 @@ func EncodeXML(src io.Reader, context []*cid.Cid, callback func(*meta.Object) er
 	}
 
 	// convert the root element
-	obj, err := encodeXML(dec, &root, context, callback)
+	obj, err := encodeXML(dec, &root, context, callback, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -118,9 +155,12 @@ func EncodeXML(src io.Reader, context []*cid.Cid, callback func(*meta.Object) er
 	return xml, nil
 }
 
-func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callback func(*meta.Object) error) (*meta.Object, error) {
+func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callback func(*meta.Object) error, opts *EncodeXMLOptions) (*meta.Object, error) {
 	// create a new node with the type as the name of the element
 	node := map[string]interface{}{"@type": el.Name.Local}
+	if opts.Reversible && el.Name.Space != "" {
+		node[namespaceKey] = el.Name.Space
+	}
 
 	// add the context
 	if len(context) > 0 {
@@ -136,7 +176,10 @@ func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callb
 		node[key] = attr.Value
 	}
 
-	// keep decoding until we see the end of the current element
+	// keep decoding until we see the end of the current element,
+	// recording the order of child elements
+	var children []string
+	names := make(map[string]struct{})
 	for {
 		token, err := dec.Token()
 		if err != nil {
@@ -148,7 +191,7 @@ func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callb
 		// xml.StartElement is the start of a child element so convert
 		// it and add it as a property
 		case xml.StartElement:
-			child, err := encodeXML(dec, &token, context, callback)
+			child, err := encodeXML(dec, &token, context, callback, opts)
 			if err != nil {
 				return nil, err
 			}
@@ -161,6 +204,8 @@ func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callb
 			case []*cid.Cid:
 				node[token.Name.Local] = append(v, child.Cid())
 			}
+			children = append(children, token.Name.Local)
+			names[token.Name.Local] = struct{}{}
 
 		// xml.CharData is text data inside the element so treat it
 		// like a value object
@@ -178,6 +223,12 @@ func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callb
 		// xml.EndElement marks the end of the current element,
 		// return it as a META object
 		case xml.EndElement:
+			// the order of children with the same name is
+			// preserved by their list, so only record the order
+			// if there are children with different names
+			if opts.Reversible && len(names) > 1 {
+				node[childrenKey] = children
+			}
 			obj, err := meta.Encode(node)
 			if err != nil {
 				return nil, err
@@ -191,3 +242,285 @@ func encodeXML(dec *xml.Decoder, el *xml.StartElement, context []*cid.Cid, callb
 		}
 	}
 }
+
+// DecodeXML writes the XML document represented by the META object graph
+// with the given CID to w, rebuilding the element names, attributes,
+// namespaces and text encoded by EncodeXML with the Reversible option.
+//
+// The CID is either that of the object returned by EncodeXML or that of any
+// element within it. Whitespace between elements is not preserved, so the
+// output is indented, and the order of text relative to child elements in
+// mixed content is lost since all of an element's text is stored in a single
+// @value property.
+//
+// An error is returned if an element has children with different names but
+// no @children property (e.g. because it was encoded without the Reversible
+// option), since the order of its children is unknown.
+func DecodeXML(store *meta.Store, id *cid.Cid, w io.Writer) error {
+	obj, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+	props, err := obj.Properties()
+	if err != nil {
+		return err
+	}
+
+	// unwrap the root element of a document
+	if props["@type"] == "meta:xml" {
+		var root *cid.Cid
+		for key, v := range props {
+			if strings.HasPrefix(key, "@") {
+				continue
+			}
+			link, ok := v.(*cid.Cid)
+			if !ok || root != nil {
+				return fmt.Errorf("metaxml: expected meta:xml object to have a single root element")
+			}
+			root = link
+		}
+		if root == nil {
+			return fmt.Errorf("metaxml: meta:xml object has no root element")
+		}
+		if obj, err = store.Get(root); err != nil {
+			return err
+		}
+		if props, err = obj.Properties(); err != nil {
+			return err
+		}
+	}
+
+	d := &xmlDecoder{store: store, w: bufio.NewWriter(w)}
+	d.printf("%s", xml.Header)
+	if err := d.writeElement(props, nil, 0); err != nil {
+		return err
+	}
+	d.printf("\n")
+	if d.err != nil {
+		return d.err
+	}
+	return d.w.Flush()
+}
+
+// xmlDecoder writes META objects encoded by EncodeXML as XML.
+type xmlDecoder struct {
+	store *meta.Store
+	w     *bufio.Writer
+	err   error
+}
+
+func (d *xmlDecoder) printf(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+// writeText writes s with any XML special characters escaped.
+func (d *xmlDecoder) writeText(s string) {
+	if d.err != nil {
+		return
+	}
+	d.err = xml.EscapeText(d.w, []byte(s))
+}
+
+// xmlScope maps namespace prefixes which are in scope to their URIs, with
+// the empty prefix mapping to the default namespace.
+type xmlScope map[string]string
+
+// prefix returns the prefix bound to the given namespace URI, preferring
+// the default namespace and then the lexically least prefix.
+func (s xmlScope) prefix(uri string) (string, bool) {
+	if uri == xmlNamespace {
+		return "xml", true
+	}
+	if s[""] == uri {
+		return "", true
+	}
+	var prefixes []string
+	for prefix, u := range s {
+		if u == uri && prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	sort.Strings(prefixes)
+	return prefixes[0], true
+}
+
+// writeElement writes the element represented by props, along with its
+// children, at the given indentation depth.
+func (d *xmlDecoder) writeElement(props map[string]interface{}, parent xmlScope, depth int) error {
+	local, ok := props["@type"].(string)
+	if !ok {
+		return fmt.Errorf("metaxml: expected element @type to be a string, got %T", props["@type"])
+	}
+
+	// split the properties into namespace declarations, attributes and
+	// children
+	scope := make(xmlScope, len(parent))
+	for prefix, uri := range parent {
+		scope[prefix] = uri
+	}
+	var decls, attrs, children []string
+	for key, v := range props {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		switch v.(type) {
+		case string:
+			switch {
+			case key == "xmlns":
+				scope[""] = v.(string)
+				decls = append(decls, key)
+			case strings.HasPrefix(key, "xmlns:"):
+				scope[strings.TrimPrefix(key, "xmlns:")] = v.(string)
+				decls = append(decls, key)
+			default:
+				attrs = append(attrs, key)
+			}
+		case *cid.Cid, []interface{}:
+			children = append(children, key)
+		}
+	}
+	sort.Strings(decls)
+	sort.Strings(attrs)
+	sort.Strings(children)
+
+	// determine the element's qualified name, declaring its namespace
+	// if it is not already in scope
+	name := local
+	if uri, ok := props[namespaceKey].(string); ok && uri != "" {
+		if prefix, ok := scope.prefix(uri); !ok {
+			scope[""] = uri
+			decls = append(decls, "xmlns")
+			props = copyProps(props, "xmlns", uri)
+		} else if prefix != "" {
+			name = prefix + ":" + local
+		}
+	} else if scope[""] != "" {
+		// the element is in no namespace, so undeclare any default
+		// namespace inherited from its parent
+		scope[""] = ""
+		decls = append(decls, "xmlns")
+		props = copyProps(props, "xmlns", "")
+	}
+
+	indent := strings.Repeat("  ", depth)
+	d.printf("%s<%s", indent, name)
+	for _, key := range decls {
+		d.printf(" %s=\"", key)
+		d.writeText(props[key].(string))
+		d.printf("\"")
+	}
+	for _, key := range attrs {
+		d.printf(" %s=\"", attrName(key, scope))
+		d.writeText(props[key].(string))
+		d.printf("\"")
+	}
+
+	// get the child elements in document order, which only needs to be
+	// recorded if there are children with different names
+	var links []*cid.Cid
+	if order, ok := props[childrenKey].([]interface{}); ok {
+		next := make(map[string]int)
+		for _, v := range order {
+			key, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("metaxml: expected %s to contain strings, got %T", childrenKey, v)
+			}
+			list := childLinks(props[key])
+			if next[key] >= len(list) {
+				return fmt.Errorf("metaxml: %s refers to missing %s element", childrenKey, key)
+			}
+			links = append(links, list[next[key]])
+			next[key]++
+		}
+	} else if len(children) > 1 {
+		return fmt.Errorf("metaxml: %s element has children named %s but no %s property", local, strings.Join(children, ", "), childrenKey)
+	} else {
+		for _, key := range children {
+			links = append(links, childLinks(props[key])...)
+		}
+	}
+
+	text, _ := props["@value"].(string)
+	switch {
+	case len(links) == 0 && text == "":
+		d.printf("/>")
+		return d.err
+	case len(links) == 0:
+		d.printf(">")
+		d.writeText(text)
+		d.printf("</%s>", name)
+		return d.err
+	}
+	d.printf(">")
+	if text != "" {
+		d.writeText(text)
+	}
+	for _, link := range links {
+		obj, err := d.store.Get(link)
+		if err != nil {
+			return err
+		}
+		child, err := obj.Properties()
+		if err != nil {
+			return err
+		}
+		d.printf("\n")
+		if err := d.writeElement(child, scope, depth+1); err != nil {
+			return err
+		}
+	}
+	d.printf("\n%s</%s>", indent, name)
+	return d.err
+}
+
+// attrName returns the qualified name of an attribute from its property
+// name, which is either a local name or a namespace URI and local name
+// separated by a colon (e.g. "http://www.w3.org/2001/XMLSchema-instance:schemaLocation").
+func attrName(key string, scope xmlScope) string {
+	i := strings.LastIndex(key, ":")
+	if i == -1 {
+		return key
+	}
+	space, local := key[:i], key[i+1:]
+	if prefix, ok := scope.prefix(space); ok && prefix != "" {
+		return prefix + ":" + local
+	}
+	// the namespace was not declared, in which case the XML decoder
+	// leaves the prefix as the namespace
+	return key
+}
+
+// childLinks returns the links to the child elements stored in a property,
+// which is either a single link or a list of links.
+func childLinks(v interface{}) []*cid.Cid {
+	switch v := v.(type) {
+	case *cid.Cid:
+		return []*cid.Cid{v}
+	case []interface{}:
+		links := make([]*cid.Cid, 0, len(v))
+		for _, x := range v {
+			if link, ok := x.(*cid.Cid); ok {
+				links = append(links, link)
+			}
+		}
+		return links
+	default:
+		return nil
+	}
+}
+
+// copyProps returns a copy of props with the given key set to v.
+func copyProps(props map[string]interface{}, key string, v interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(props)+1)
+	for k, x := range props {
+		out[k] = x
+	}
+	out[key] = v
+	return out
+}
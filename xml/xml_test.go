@@ -21,7 +21,14 @@ package metaxml
 
 import (
 	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/ipfs/go-cid"
@@ -37,7 +44,7 @@ func TestEncodeXML(t *testing.T) {
 
 	// encode the XML with the context, storing objects in memory
 	store := meta.NewStore(datastore.NewMapDatastore())
-	xml, err := EncodeXML(bytes.NewReader(testXML), context, store.Put)
+	xml, err := EncodeXML(bytes.NewReader(testXML), context, store.Put, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -182,6 +189,121 @@ func TestEncodeXMLSchema(t *testing.T) {
 	}
 }
 
+func TestDecodeXML(t *testing.T) {
+	files, err := filepath.Glob("../ern/testdata/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("missing ERN test files")
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDecodeXML(t, src)
+		})
+	}
+	t.Run("catalog", func(t *testing.T) {
+		testDecodeXML(t, testXML)
+	})
+
+	// check an element with children under several names but no
+	// @children property is rejected
+	t.Run("unordered", func(t *testing.T) {
+		store := meta.NewStore(datastore.NewMapDatastore())
+		put := func(v map[string]interface{}) *meta.Object {
+			obj := meta.MustEncode(v)
+			if err := store.Put(obj); err != nil {
+				t.Fatal(err)
+			}
+			return obj
+		}
+		a := put(map[string]interface{}{"@type": "a"})
+		b := put(map[string]interface{}{"@type": "b"})
+		root := put(map[string]interface{}{"@type": "root", "a": a.Cid(), "b": b.Cid()})
+		if err := DecodeXML(store, root.Cid(), ioutil.Discard); err == nil {
+			t.Fatal("expected error decoding element without @children")
+		}
+	})
+}
+
+// testDecodeXML checks that decoding the META objects encoded from src
+// produces semantically equal XML.
+func testDecodeXML(t *testing.T, src []byte) {
+	store := meta.NewStore(datastore.NewMapDatastore())
+	obj, err := EncodeXML(bytes.NewReader(src), nil, store.Put, &EncodeXMLOptions{Reversible: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := DecodeXML(store, obj.Cid(), &out); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := parseTestElement(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := parseTestElement(&out)
+	if err != nil {
+		t.Fatalf("error parsing decoded XML: %s\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("decoded XML differs from the original:\n%s", out.String())
+	}
+}
+
+// testElement is a canonical representation of an XML element used to
+// compare documents regardless of whitespace, namespace prefixes and the
+// order of attributes.
+type testElement struct {
+	Name     xml.Name
+	Attr     []string
+	Text     string
+	Children []*testElement
+}
+
+func parseTestElement(r io.Reader) (*testElement, error) {
+	dec := xml.NewDecoder(r)
+	var stack []*testElement
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			el := &testElement{Name: token.Name}
+			for _, attr := range token.Attr {
+				// namespace declarations are compared via the
+				// resolved element and attribute names
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				el.Attr = append(el.Attr, attr.Name.Space+" "+attr.Name.Local+"="+attr.Value)
+			}
+			sort.Strings(el.Attr)
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, el)
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return el, nil
+			}
+		case xml.CharData:
+			if text := strings.TrimSpace(string(token)); text != "" {
+				stack[len(stack)-1].Text += text
+			}
+		}
+	}
+}
+
 // testXML is used to test encoding XML, adapted from
 // http://www.service-architecture.com/articles/object-oriented-databases/xml_file_for_complex_data.html
 var testXML = []byte(`